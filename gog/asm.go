@@ -0,0 +1,159 @@
+package gog
+
+import (
+	"go/token"
+	"regexp"
+	"strings"
+
+	"go/types"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// AsmSymbol is a symbol named by a TEXT, GLOBL or DATA directive in a
+// Plan 9 assembly (.s) file.
+type AsmSymbol struct {
+	// Directive is "TEXT", "GLOBL" or "DATA".
+	Directive string
+
+	// PkgPath is the import path of the package that the symbol
+	// belongs to, or the empty string for the package whose file it
+	// is (as in ·name).
+	PkgPath string
+
+	// Name is the symbol's name, without its package (e.g., "add" for
+	// ·add, or "T.M" for ·T·M or ·(*T).M).
+	Name string
+
+	// Static is whether the symbol is only visible in its file (as in
+	// ·name<>).
+	Static bool
+
+	File      string
+	IdentSpan [2]uint32
+	DeclSpan  [2]uint32
+}
+
+// asmSymbolRx matches a TEXT, GLOBL or DATA directive and the package
+// path and name of its symbol.
+var asmSymbolRx = regexp.MustCompile(`^[ \t]*(TEXT|GLOBL|DATA)[ \t]+([^\s(·]*)·(\(\*?[\pL_][\pL\pN_]*\)\.[\pL_][\pL\pN_]*|[\pL_][\pL\pN_]*(?:·[\pL_][\pL\pN_]*)?)(<>)?(?:\+\d+)?\(SB\)`)
+
+// ScanAsmFile returns the symbols named by the TEXT, GLOBL and DATA
+// directives in src, the contents of the assembly file filename.
+func ScanAsmFile(filename string, src []byte) []*AsmSymbol {
+	var syms []*AsmSymbol
+	off := 0
+	for _, line := range strings.SplitAfter(string(src), "\n") {
+		if m := asmSymbolRx.FindStringSubmatchIndex(line); m != nil {
+			sym := &AsmSymbol{
+				Directive: line[m[2]:m[3]],
+				PkgPath:   strings.NewReplacer("∕", "/", "·", ".").Replace(line[m[4]:m[5]]),
+				Name:      asmName(line[m[6]:m[7]]),
+				Static:    m[8] != -1,
+				File:      filename,
+				IdentSpan: [2]uint32{uint32(off + m[6]), uint32(off + m[7])},
+				DeclSpan:  [2]uint32{uint32(off + m[2]), uint32(off + len(strings.TrimRight(line, "\r\n")))},
+			}
+			syms = append(syms, sym)
+		}
+		off += len(line)
+	}
+	return syms
+}
+
+// asmName returns the Go-style name of the assembly symbol name:
+// "T.M" for "T·M", "(*T).M" or "(T).M".
+func asmName(name string) string {
+	if strings.HasPrefix(name, "(") {
+		i := strings.Index(name, ")")
+		return strings.TrimPrefix(name[1:i], "*") + name[i+1:]
+	}
+	return strings.Replace(name, "·", ".", -1)
+}
+
+// lookupAsmSymbol returns the Go func, method or var in the package
+// being graphed that sym names, or nil if there is none.
+func (g *grapher) lookupAsmSymbol(sym *AsmSymbol) types.Object {
+	if sym.Static || (sym.PkgPath != "" && sym.PkgPath != g.typesPkg.Path()) {
+		return nil
+	}
+	names := strings.SplitN(sym.Name, ".", 2)
+	obj := g.typesPkg.Scope().Lookup(names[0])
+	if len(names) == 1 || obj == nil {
+		return obj
+	}
+	tn, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	m, _, _ := types.LookupFieldOrMethod(tn.Type(), true, g.typesPkg, names[1])
+	if m, ok := m.(*types.Func); ok {
+		return m
+	}
+	return nil
+}
+
+// asmSymbolPrefix is the first element of the paths of the defs for
+// assembly symbols that don't implement Go defs. It contains a "$" so
+// that it can't collide with the path of a Go def (e.g., a method of a
+// type named asm).
+const asmSymbolPrefix = "asm$"
+
+// emitAsmSymbols emits refs from the assembly symbols in
+// Options.AsmSymbols to the Go defs they implement (or, for symbols in
+// other packages, that they refer to), and defs for the symbols that
+// have no Go declaration.
+func (g *grapher) emitAsmSymbols() {
+	asmDefs := make(map[string]*DefKey)
+	for _, sym := range g.opt.AsmSymbols {
+		var key *DefKey
+		if obj := g.lookupAsmSymbol(sym); obj != nil {
+			key, _ = g.defInfo(obj)
+		} else if sym.PkgPath != "" && sym.PkgPath != g.typesPkg.Path() {
+			key = &DefKey{PackageImportPath: sym.PkgPath, Path: strings.Split(sym.Name, ".")}
+		} else {
+			name := sym.Name
+			if sym.Static {
+				// static symbols are file-scoped
+				name += uniqID(token.Position{Filename: sym.File})
+			}
+			key = asmDefs[name]
+			if key == nil {
+				key = &DefKey{PackageImportPath: g.typesPkg.Path(), Path: []string{asmSymbolPrefix, name}}
+				asmDefs[name] = key
+				g.output.Defs = append(g.output.Defs, &Def{
+					Name:   sym.Name,
+					DefKey: key,
+
+					File:      sym.File,
+					IdentSpan: sym.IdentSpan,
+					DeclSpan:  sym.DeclSpan,
+
+					HeaderSpan: sym.DeclSpan,
+					FullSpan:   sym.DeclSpan,
+
+					DefInfo: definfo.DefInfo{
+						PkgScope:     !sym.Static,
+						PkgName:      g.typesPkg.Name(),
+						Kind:         definfo.AsmSymbol,
+						AsmDirective: sym.Directive,
+					},
+				})
+				g.output.Refs = append(g.output.Refs, &Ref{
+					Unit:  g.typesPkg.Path(),
+					File:  sym.File,
+					Span:  sym.IdentSpan,
+					Def:   key,
+					IsDef: true,
+				})
+				continue
+			}
+		}
+		g.output.Refs = append(g.output.Refs, &Ref{
+			Unit: g.typesPkg.Path(),
+			File: sym.File,
+			Span: sym.IdentSpan,
+			Def:  key,
+		})
+	}
+}
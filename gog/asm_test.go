@@ -0,0 +1,66 @@
+package gog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAsmSymbols(t *testing.T) {
+	src := `package foo
+
+func add(a, b int) int
+
+type T struct{}
+
+func (*T) M()
+`
+	asm := `#include "textflag.h"
+
+// func add(a, b int) int
+TEXT ·add(SB), NOSPLIT, $0-24
+	MOVQ a+0(FP), AX
+	CALL runtime·entersyscall(SB)
+	RET
+
+TEXT ·(*T).M(SB), NOSPLIT, $0
+	RET
+
+TEXT ·helper(SB), NOSPLIT, $0
+	RET
+
+DATA tbl<>+0(SB)/8, $1
+DATA ·tbl<>+0(SB)/8, $1
+GLOBL ·tbl<>(SB), RODATA, $8
+
+TEXT internal∕cpu·cpuid(SB), NOSPLIT, $0
+	RET
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	opt := Options{AsmSymbols: ScanAsmFile("foo_amd64.s", []byte(asm))}
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, opt)
+
+	var refs []string
+	for _, ref := range output.Refs {
+		if ref.File != "foo_amd64.s" {
+			continue
+		}
+		s := asm[ref.Span[0]:ref.Span[1]] + " -> " + ref.Def.PackageImportPath + "#" + strings.Join(ref.Def.Path, "/")
+		if ref.IsDef {
+			s += " (def)"
+		}
+		refs = append(refs, s)
+	}
+	want := []string{
+		"add -> foo#add",
+		"(*T).M -> foo#T/M",
+		"helper -> foo#asm$/helper (def)",
+		"tbl -> foo#asm$/tbl$foo_amd64.s0 (def)",
+		"tbl -> foo#asm$/tbl$foo_amd64.s0",
+		"cpuid -> internal/cpu#cpuid",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs\n%s\nwant\n%s", strings.Join(refs, "\n"), strings.Join(want, "\n"))
+	}
+}
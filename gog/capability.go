@@ -0,0 +1,137 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// refCapability returns the capability (definfo.CapabilityUnsafe, etc.)
+// that code needs in order to make ref, or the empty string if ref is
+// not sensitive. Uses of cgo are found by syntax instead (see
+// addCapabilities), since C symbols need not be declared in the
+// package.
+func refCapability(ref *Ref) string {
+	switch path := ref.Def.PackageImportPath; {
+	case path == "unsafe":
+		return definfo.CapabilityUnsafe
+	case path == "reflect":
+		return definfo.CapabilityReflect
+	case path == "syscall" || strings.HasPrefix(path, "golang.org/x/sys/"):
+		return definfo.CapabilitySyscall
+	case path == "os/exec":
+		return definfo.CapabilityExec
+	}
+	return ""
+}
+
+// addCapabilities sets the capabilities of each package-level def (a
+// func, method, var, const or type) to those its declaration's refs
+// (and C.name selectors, for cgo) need, and the capabilities of the
+// package def to the union of them.
+func (g *grapher) addCapabilities(files []*ast.File) {
+	// the top-level declarations in each file and the objects they
+	// declare, in order
+	type decl struct {
+		start, end uint32
+		objs       []types.Object
+	}
+	decls := make(map[string][]decl)
+	for _, f := range files {
+		filename := g.fset.Position(f.Pos()).Filename
+		add := func(n ast.Node, idents ...*ast.Ident) {
+			var objs []types.Object
+			for _, ident := range idents {
+				if obj := g.typesInfo.Defs[ident]; obj != nil {
+					objs = append(objs, obj)
+				}
+			}
+			span := makeSpan(g.fset, n)
+			decls[filename] = append(decls[filename], decl{span[0], span[1], objs})
+		}
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				add(d, d.Name)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						add(spec, spec.Names...)
+					case *ast.TypeSpec:
+						add(spec, spec.Name)
+					}
+				}
+			}
+		}
+	}
+
+	caps := make(map[*DefKey]map[string]bool)
+	pkgCaps := make(map[string]bool)
+	use := func(c, filename string, span [2]uint32) {
+		fileDecls := decls[filename]
+		i := sort.Search(len(fileDecls), func(i int) bool { return fileDecls[i].end >= span[1] })
+		if i == len(fileDecls) || fileDecls[i].start > span[0] {
+			return
+		}
+		pkgCaps[c] = true
+		for _, obj := range fileDecls[i].objs {
+			key, _ := g.defInfo(obj)
+			if caps[key] == nil {
+				caps[key] = make(map[string]bool)
+			}
+			caps[key][c] = true
+		}
+	}
+	for _, ref := range g.output.Refs {
+		if c := refCapability(ref); c != "" && !ref.IsDef {
+			use(c, ref.File, ref.Span)
+		}
+	}
+	for _, f := range files {
+		if !importsC(f) {
+			continue
+		}
+		filename := g.fset.Position(f.Pos()).Filename
+		ast.Inspect(f, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok && g.isCPkg(sel.X) {
+				use(definfo.CapabilityCgo, filename, makeSpan(g.fset, sel))
+			}
+			return true
+		})
+	}
+
+	for _, def := range g.output.Defs {
+		if def.Kind == definfo.Package && def.PackageImportPath == g.typesPkg.Path() && len(def.Path) == 0 {
+			def.Capabilities = sortedKeys(pkgCaps)
+		} else if c := caps[def.DefKey]; c != nil {
+			def.Capabilities = sortedKeys(c)
+		}
+	}
+}
+
+// importsC reports whether f imports the pseudo-package "C" (i.e., is
+// a cgo file).
+func importsC(f *ast.File) bool {
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package gog
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	src := `package foo
+
+import (
+	"os/exec"
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+func Run(name string) error { return exec.Command(name).Run() }
+
+func Size(x int) uintptr {
+	n := unsafe.Sizeof(x)
+	return n
+}
+
+type T struct {
+	p unsafe.Pointer
+}
+
+func (t T) Kind() string { return reflect.TypeOf(t).Kind().String() }
+
+var pid, other = syscall.Getpid(), 1
+
+func Plain() int { return 1 }
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.go"})
+	pkgInfo := prog.Created[0]
+
+	if output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{}); len(output.Defs[0].Capabilities) != 0 {
+		t.Errorf("got package capabilities %q without Options.Capabilities", output.Defs[0].Capabilities)
+	}
+
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{Capabilities: true})
+	var got []string
+	for _, def := range output.Defs {
+		if len(def.Capabilities) > 0 {
+			got = append(got, strings.Join(def.Path, "/")+": "+strings.Join(def.Capabilities, ","))
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		": exec,reflect,syscall,unsafe",
+		"Run: exec",
+		"Size: unsafe",
+		"T/Kind: reflect",
+		"T: unsafe",
+		"other: syscall",
+		"pid: syscall",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got capabilities\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCgoCapability(t *testing.T) {
+	src := `package foo
+
+// #include <stdlib.h>
+//
+// static int twice(int n) { return 2 * n; }
+import "C"
+
+import "unsafe"
+
+func Free(p unsafe.Pointer) { C.free(p) }
+
+func Twice(n int) int { return int(C.twice(C.int(n))) }
+
+func Plain() int { return 1 }
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.go"})
+	pkgInfo := prog.Created[0]
+
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{Capabilities: true})
+	var got []string
+	for _, def := range output.Defs {
+		if len(def.Capabilities) > 0 {
+			got = append(got, strings.Join(def.Path, "/")+": "+strings.Join(def.Capabilities, ","))
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		": cgo,unsafe",
+		"Free: cgo,unsafe",
+		"Twice: cgo",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got capabilities\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
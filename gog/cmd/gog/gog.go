@@ -5,8 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"go/build"
+	"go/parser"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/tools/go/loader"
@@ -15,6 +17,14 @@ import (
 )
 
 var buildTags = flag.String("tags", "", "a list of build tags to consider satisfied")
+var markdownDocs = flag.Bool("markdown-docs", false, "also emit docs rendered as Markdown")
+var serializedName = flag.String("serialized-name", "", "instead of graphing, list the struct fields serialized as this name according to their tags (optionally prefixed with a tag key, as in json:user_id)")
+var positionEncoding = flag.String("position-encoding", "", "also emit 0-based line/character ranges in this encoding ("+strings.Join(gog.PositionEncodings, ", ")+")")
+var omitGenerated = flag.Bool("omit-generated", false, "don't emit defs, refs, docs and annotations in generated files")
+var capabilities = flag.Bool("capabilities", false, "record the sensitive capabilities (unsafe, reflect, syscall, exec, cgo) that each def and package uses")
+var metrics = flag.Bool("metrics", false, "compute size and complexity metrics of funcs, methods and types")
+var promotingTypes = flag.String("promoting-types", "", "instead of graphing, list the struct types that promote this field or method (given as importpath#Type.Name, as in bytes#Buffer.Len)")
+var omitComments = flag.String("omit-comments", "", "a list of classes of unattached comments not to emit ("+strings.Join(gog.CommentClasses, ", ")+")")
 
 func main() {
 	flag.Usage = func() {
@@ -46,6 +56,9 @@ func main() {
 		}
 	}
 
+	// Doc comments are needed for docs and deprecations.
+	config.ParserMode |= parser.ParseComments
+
 	extraArgs, err := config.FromArgs(flag.Args(), true)
 	if err != nil {
 		log.Fatal(err)
@@ -74,14 +87,100 @@ func main() {
 		pkgs = append(pkgs, pkg)
 	}
 
+	opt := gog.Options{
+		IncludeDocs:      true,
+		MarkdownDocs:     *markdownDocs,
+		PositionEncoding: *positionEncoding,
+		OmitGenerated:    *omitGenerated,
+		Capabilities:     *capabilities,
+		Metrics:          *metrics,
+	}
+	if *positionEncoding != "" {
+		var ok bool
+		for _, enc := range gog.PositionEncodings {
+			ok = ok || enc == *positionEncoding
+		}
+		if !ok {
+			log.Fatalf("unrecognized position encoding %q (valid encodings are %s)", *positionEncoding, strings.Join(gog.PositionEncodings, ", "))
+		}
+	}
+	if *omitComments != "" {
+		opt.OmitComments = make(map[string]bool)
+		for _, class := range strings.Split(*omitComments, ",") {
+			var ok bool
+			for _, c := range gog.CommentClasses {
+				ok = ok || c == class
+			}
+			if !ok {
+				log.Fatalf("unrecognized comment class %q (valid classes are %s)", class, strings.Join(gog.CommentClasses, ", "))
+			}
+			opt.OmitComments[class] = true
+		}
+	}
+
+	opt.DepDeprecations = make(map[string]gog.Deprecations)
+	for _, pkg := range prog.AllPackages {
+		opt.DepDeprecations[pkg.Pkg.Path()] = gog.FileDeprecations(prog.Fset, pkg.Files)
+	}
+
 	var output gog.Output
 	for _, pkg := range pkgs {
-		o := gog.Graph(prog.Fset, pkg.Files, pkg.Pkg, &pkg.Info, true)
+		opt := opt
+		if len(pkg.Files) > 0 {
+			opt.Files = pkgDirFiles(filepath.Dir(prog.Fset.Position(pkg.Files[0].Pos()).Filename))
+		}
+		o := gog.Graph(prog.Fset, pkg.Files, pkg.Pkg, &pkg.Info, opt)
 		output.Append(o)
 	}
 
+	if *serializedName != "" {
+		var key string
+		name := *serializedName
+		if i := strings.Index(name, ":"); i != -1 {
+			key, name = name[:i], name[i+1:]
+		}
+		enc := json.NewEncoder(os.Stdout)
+		for _, def := range gog.FieldsBySerializedName(output.Defs, key, name) {
+			if err := enc.Encode(def); err != nil {
+				log.Fatal(err)
+			}
+		}
+		return
+	}
+
+	if *promotingTypes != "" {
+		i := strings.Index(*promotingTypes, "#")
+		if i == -1 {
+			log.Fatalf("invalid -promoting-types def %q (want importpath#Type.Name)", *promotingTypes)
+		}
+		def := &gog.DefKey{PackageImportPath: (*promotingTypes)[:i], Path: strings.Split((*promotingTypes)[i+1:], ".")}
+		enc := json.NewEncoder(os.Stdout)
+		for _, key := range gog.PromotingTypes(output.Relations, def) {
+			if err := enc.Encode(key); err != nil {
+				log.Fatal(err)
+			}
+		}
+		return
+	}
+
 	err = json.NewEncoder(os.Stdout).Encode(&output)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// pkgDirFiles returns the files in dir and its subdirectories, relative
+// to dir (with slash separators), for matching //go:embed patterns.
+func pkgDirFiles(dir string) []string {
+	var files []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
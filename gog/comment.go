@@ -0,0 +1,98 @@
+package gog
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"regexp"
+	"strings"
+)
+
+// Classes of comments that are not attached to any def. Each is
+// emitted as a Doc whose Format is CommentFormat(class).
+const (
+	// CommentLicense is a copyright or license header preceding the
+	// package clause.
+	CommentLicense = "license"
+
+	// CommentBuildConstraint is a "//go:build" or "// +build" line.
+	CommentBuildConstraint = "build-constraint"
+
+	// CommentDirective is a compiler or tool directive, such as
+	// "//go:noinline", "//line" or "//export".
+	CommentDirective = "directive"
+
+	// CommentGenerate is a "//go:generate" directive.
+	CommentGenerate = "generate"
+
+	// CommentNote is a "BUG(who)", "TODO(who)" or "TODO" note.
+	CommentNote = "note"
+)
+
+// CommentClasses lists all of the comment classes.
+var CommentClasses = []string{CommentLicense, CommentBuildConstraint, CommentDirective, CommentGenerate, CommentNote}
+
+// CommentFormat returns the Doc format used for unattached comments
+// of the given class.
+func CommentFormat(class string) string {
+	return "text/x-go-" + class
+}
+
+var (
+	// directiveLine matches the directive comments recognized by the
+	// go tool and compilers (see (*ast.CommentGroup).Text).
+	directiveLine = regexp.MustCompile(`^//(line |extern |export |[a-z0-9]+:[a-z0-9])`)
+
+	// noteMarker matches "BUG(who):" style markers, as in go/doc, and
+	// bare "TODO" notes.
+	noteMarker = regexp.MustCompile(`^[ \t]*([A-Z][A-Z]+\([^)]+\):?|TODO\b)`)
+
+	licenseWords = []string{"copyright", "license", "licence", "spdx-license-identifier"}
+)
+
+// classifyComment returns the class of c, an unattached comment in f,
+// or the empty string if c is an ordinary comment.
+func classifyComment(f *ast.File, c *ast.CommentGroup) string {
+	build, generate, directive := true, true, true
+	for _, l := range c.List {
+		build = build && (constraint.IsGoBuild(l.Text) || constraint.IsPlusBuild(l.Text))
+		generate = generate && strings.HasPrefix(l.Text, "//go:generate ")
+		directive = directive && directiveLine.MatchString(l.Text)
+	}
+	switch {
+	case build:
+		return CommentBuildConstraint
+	case generate:
+		return CommentGenerate
+	case directive:
+		return CommentDirective
+	}
+
+	text := c.Text()
+	if c.End() < f.Package && c != f.Doc {
+		lower := strings.ToLower(text)
+		for _, w := range licenseWords {
+			if strings.Contains(lower, w) {
+				return CommentLicense
+			}
+		}
+	}
+	if noteMarker.MatchString(text) {
+		return CommentNote
+	}
+	return ""
+}
+
+// commentData returns the Data of a Doc for an unattached comment of
+// the given class. Build constraints and directives are stripped by
+// (*ast.CommentGroup).Text, so they are kept verbatim.
+func commentData(c *ast.CommentGroup, class string) string {
+	switch class {
+	case CommentBuildConstraint, CommentDirective, CommentGenerate:
+		lines := make([]string, len(c.List))
+		for i, l := range c.List {
+			lines[i] = l.Text
+		}
+		return strings.Join(lines, "\n") + "\n"
+	}
+	return c.Text()
+}
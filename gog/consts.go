@@ -0,0 +1,73 @@
+package gog
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// constValue returns the exact value of c and the name of its kind
+// (as in definfo.DefInfo.ConstValue and ConstKind). Floats are written
+// in decimal form if that is exact, and as fractions otherwise.
+func constValue(c *types.Const) (val, kind string) {
+	v := c.Val()
+	if v == nil || v.Kind() == constant.Unknown {
+		return "", ""
+	}
+	if v.Kind() == constant.Float {
+		s := v.String()
+		if lit := constant.MakeFromLiteral(s, token.FLOAT, 0); lit.Kind() != constant.Unknown && constant.Compare(lit, token.EQL, v) {
+			return s, v.Kind().String()
+		}
+	}
+	return v.ExactString(), v.Kind().String()
+}
+
+// buildEnums groups the consts declared in const blocks that use iota
+// by their (named, package-level) type, so that each type's def can
+// list its values.
+func (g *grapher) buildEnums() {
+	g.enums = make(map[*types.TypeName][]definfo.EnumValue)
+	iotaObj := types.Universe.Lookup("iota")
+	for _, f := range g.files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST || !g.usesObj(gd, iotaObj) {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					c, ok := g.typesInfo.Defs[name].(*types.Const)
+					if !ok || name.Name == "_" {
+						continue
+					}
+					named, ok := c.Type().(*types.Named)
+					if !ok || named.Obj().Pkg() != g.typesPkg || !isPkgLevel(named.Obj()) {
+						continue
+					}
+					val, _ := constValue(c)
+					g.enums[named.Obj()] = append(g.enums[named.Obj()], definfo.EnumValue{
+						Name:  c.Name(),
+						Value: val,
+						Def:   g.objDefKey(c),
+					})
+				}
+			}
+		}
+	}
+}
+
+// usesObj reports whether node contains a use of obj.
+func (g *grapher) usesObj(node ast.Node, obj types.Object) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && g.typesInfo.Uses[ident] == obj {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
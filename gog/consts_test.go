@@ -0,0 +1,74 @@
+package gog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestConstValues(t *testing.T) {
+	src := `package foo
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	_
+	Blue
+)
+
+const Black Color = -1
+
+const (
+	S = "a\tb"
+	F = 1.5
+	G = 1.0 / 3
+	B = S == ""
+	X uint8 = 1 << 7
+)
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	type constValue struct{ value, kind string }
+	values := make(map[string]constValue)
+	var enum []definfo.EnumValue
+	for _, def := range output.Defs {
+		if def.ConstValue != "" {
+			values[strings.Join(def.Path, "/")] = constValue{def.ConstValue, def.ConstKind}
+		}
+		if def.Name == "Color" {
+			enum = def.EnumValues
+		}
+	}
+	wantValues := map[string]constValue{
+		"Red":   {"0", "Int"},
+		"Green": {"1", "Int"},
+		"Blue":  {"3", "Int"},
+		"Black": {"-1", "Int"},
+		"S":     {`"a\tb"`, "String"},
+		"F":     {"1.5", "Float"},
+		"G":     {"1/3", "Float"},
+		"B":     {"false", "Bool"},
+		"X":     {"128", "Int"},
+	}
+	if !reflect.DeepEqual(values, wantValues) {
+		t.Errorf("got const values %v, want %v", values, wantValues)
+	}
+
+	key := func(name string) *definfo.DefKey {
+		return &definfo.DefKey{PackageImportPath: "foo", Path: []string{name}}
+	}
+	wantEnum := []definfo.EnumValue{
+		{Name: "Red", Value: "0", Def: key("Red")},
+		{Name: "Green", Value: "1", Def: key("Green")},
+		{Name: "Blue", Value: "3", Def: key("Blue")},
+	}
+	if !reflect.DeepEqual(enum, wantEnum) {
+		t.Errorf("got enum values %+v, want %+v", enum, wantEnum)
+	}
+}
@@ -0,0 +1,524 @@
+package gog
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// CSymbol is a C function, type, macro, variable, enumerator or
+// struct, union or enum tag declared in a cgo preamble or in one of a
+// package's C source or header files, which Go code can refer to as
+// C.Name.
+type CSymbol struct {
+	// Name is the name of the symbol as referred to from Go (e.g.,
+	// "foo" for C.foo, or "struct_foo" for C.struct_foo).
+	Name string
+
+	// Kind is the kind of C declaration (definfo.CFunc, etc.).
+	Kind string
+
+	File      string
+	IdentSpan [2]uint32
+	DeclSpan  [2]uint32
+
+	// Body is whether the declaration is a definition with a body
+	// (for functions).
+	Body bool
+}
+
+// ScanCFile returns the symbols declared in src, the contents of the C
+// source or header file filename. It is a lightweight scanner, not a C
+// parser: it recognizes the usual forms of top-level declarations and
+// #define directives, without expanding macros or following #include
+// directives.
+func ScanCFile(filename string, src []byte) []*CSymbol {
+	return scanC(filename, string(src), 0)
+}
+
+// preambleSymbols returns the symbols declared in the cgo preambles
+// (the doc comments of `import "C"`) of f.
+func (g *grapher) preambleSymbols(f *ast.File) []*CSymbol {
+	var syms []*CSymbol
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			imp, ok := spec.(*ast.ImportSpec)
+			if !ok || imp.Path.Value != `"C"` {
+				continue
+			}
+			doc := imp.Doc
+			if doc == nil && !gd.Lparen.IsValid() {
+				doc = gd.Doc
+			}
+			if doc == nil {
+				continue
+			}
+			start := g.fset.Position(doc.Pos())
+			syms = append(syms, scanC(start.Filename, preambleSource(g.fset, doc), start.Offset)...)
+		}
+	}
+	return syms
+}
+
+// preambleSource returns the C source in the comments of doc, with
+// the comment markers replaced by spaces so that offsets within it are
+// relative to the start of doc.
+func preambleSource(fset *token.FileSet, doc *ast.CommentGroup) string {
+	base := fset.Position(doc.Pos()).Offset
+	var buf []byte
+	for _, c := range doc.List {
+		off := fset.Position(c.Pos()).Offset - base
+		for len(buf) < off {
+			buf = append(buf, '\n')
+		}
+		text := []byte(c.Text)
+		if strings.HasPrefix(c.Text, "/*") {
+			copy(text[len(text)-2:], "  ")
+		}
+		copy(text, "  ")
+		buf = append(buf[:off], text...)
+	}
+	return string(buf)
+}
+
+var (
+	cDefineRx = regexp.MustCompile(`^[ \t]*#[ \t]*define[ \t]+([A-Za-z_]\w*)`)
+
+	// cKeywords are the C keywords and the names of C's basic types
+	// that may appear in declarations but are never declared names.
+	cKeywords = map[string]bool{}
+)
+
+func init() {
+	for _, kw := range strings.Fields(`auto break case char const continue default do double else enum extern
+		float for goto if inline int long register restrict return short signed sizeof static struct
+		switch typedef union unsigned void volatile while _Bool _Complex _Noreturn _Atomic _Thread_local
+		__attribute__ __inline __inline__ __restrict __extension__ __asm__ asm`) {
+		cKeywords[kw] = true
+	}
+}
+
+// cToken is an identifier or punctuation character in C source.
+type cToken struct {
+	text  string
+	start int
+}
+
+func (t cToken) end() int { return t.start + len(t.text) }
+
+func (t cToken) isIdent() bool {
+	c := t.text[0]
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// scanC returns the symbols declared in src, which starts at offset
+// base in filename.
+func scanC(filename, src string, base int) []*CSymbol {
+	s := &cScanner{filename: filename, base: base}
+	src = blankCComments(src)
+
+	// Handle (and then blank) preprocessor directives.
+	lines := strings.SplitAfter(src, "\n")
+	b := []byte(src)
+	off := 0
+	continued := false
+	for _, line := range lines {
+		directive := continued || strings.HasPrefix(strings.TrimLeft(line, " \t"), "#")
+		if directive {
+			if m := cDefineRx.FindStringSubmatchIndex(line); m != nil && !continued {
+				s.add(line[m[2]:m[3]], definfo.CMacro, off+m[2], off+m[3], off, off+len(strings.TrimRight(line, "\r\n")), false)
+			}
+			for i := off; i < off+len(line); i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+			continued = strings.HasSuffix(strings.TrimRight(line, "\r\n"), "\\")
+		}
+		off += len(line)
+	}
+	src = string(b)
+
+	s.scanDecls(tokenizeC(src))
+	return s.syms
+}
+
+type cScanner struct {
+	filename string
+	base     int
+	syms     []*CSymbol
+}
+
+func (s *cScanner) add(name, kind string, identStart, identEnd, declStart, declEnd int, body bool) {
+	s.syms = append(s.syms, &CSymbol{
+		Name:      name,
+		Kind:      kind,
+		File:      s.filename,
+		IdentSpan: [2]uint32{uint32(s.base + identStart), uint32(s.base + identEnd)},
+		DeclSpan:  [2]uint32{uint32(s.base + declStart), uint32(s.base + declEnd)},
+		Body:      body,
+	})
+}
+
+// blankCComments replaces the comments and the contents of string and
+// character literals in src with spaces (keeping newlines, and the
+// backslashes of line continuations in literals, so that lines and
+// directives still span the same lines).
+func blankCComments(src string) string {
+	b := []byte(src)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '/':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '*':
+			b[i], b[i+1] = ' ', ' '
+			for i += 2; i < len(b) && !(b[i] == '*' && i+1 < len(b) && b[i+1] == '/'); i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+			if i < len(b) {
+				b[i], b[i+1] = ' ', ' '
+				i++
+			}
+		case b[i] == '"' || b[i] == '\'':
+			q := b[i]
+			for i++; i < len(b) && b[i] != q && b[i] != '\n'; i++ {
+				if b[i] == '\\' && i+1 < len(b) {
+					// keep line continuations
+					j := i + 1
+					if b[j] == '\r' && j+1 < len(b) {
+						j++
+					}
+					if b[j] == '\n' {
+						i = j
+						continue
+					}
+					b[i] = ' '
+					i++
+				}
+				b[i] = ' '
+			}
+		}
+	}
+	return string(b)
+}
+
+func tokenizeC(src string) []cToken {
+	var toks []cToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || 'a' <= src[j] && src[j] <= 'z' || 'A' <= src[j] && src[j] <= 'Z' || '0' <= src[j] && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, cToken{src[i:j], i})
+			i = j
+		case '0' <= c && c <= '9':
+			j := i + 1
+			for j < len(src) && (src[j] == '.' || src[j] == '_' || 'a' <= src[j] && src[j] <= 'z' || 'A' <= src[j] && src[j] <= 'Z' || '0' <= src[j] && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, cToken{"0", i})
+			i = j
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		default:
+			toks = append(toks, cToken{src[i : i+1], i})
+			i++
+		}
+	}
+	return toks
+}
+
+// scanDecls finds the top-level declarations in toks, each of which is
+// terminated by a ";" or (for a function definition) by the "}" that
+// ends its body.
+func (s *cScanner) scanDecls(toks []cToken) {
+	start, depth := 0, 0
+	for i, t := range toks {
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth < 0 {
+				// unbalanced; resynchronize
+				depth, start = 0, i+1
+				continue
+			}
+			if depth == 0 && s.isFuncBody(toks[start:i+1]) {
+				s.decl(toks[start:i+1], true)
+				start = i + 1
+			}
+		case ";":
+			if depth == 0 {
+				s.decl(toks[start:i+1], false)
+				start = i + 1
+			}
+		}
+	}
+}
+
+// isFuncBody reports whether decl, which ends with "}", is a function
+// definition: whether the "{" that opens its last brace block follows
+// a ")" at the top level.
+func (s *cScanner) isFuncBody(decl []cToken) bool {
+	depth := 0
+	for i := len(decl) - 1; i >= 0; i-- {
+		switch decl[i].text {
+		case "}":
+			depth++
+		case "{":
+			depth--
+			if depth == 0 {
+				return i > 0 && decl[i-1].text == ")"
+			}
+		}
+	}
+	return false
+}
+
+// decl adds the symbols declared by the declaration decl.
+func (s *cScanner) decl(decl []cToken, body bool) {
+	if len(decl) == 0 {
+		return
+	}
+	declStart := decl[0].start
+	declEnd := decl[len(decl)-1].start + 1
+	typedef := decl[0].text == "typedef"
+
+	// struct, union and enum tags, and enumerators
+	for i := 0; i+2 < len(decl); i++ {
+		kw := decl[i].text
+		if (kw == "struct" || kw == "union" || kw == "enum") && decl[i+1].isIdent() && decl[i+2].text == "{" {
+			s.add(kw+"_"+decl[i+1].text, kw, decl[i+1].start, decl[i+1].end(), declStart, declEnd, false)
+		}
+		if kw == "enum" {
+			j := i + 1
+			if j < len(decl) && decl[j].isIdent() {
+				j++
+			}
+			if j < len(decl) && decl[j].text == "{" {
+				s.enumerators(decl[j:], declStart, declEnd)
+			}
+		}
+	}
+
+	// declarators, which are separated by commas outside of braces,
+	// parentheses and brackets
+	for _, d := range splitCTokens(decl, ",") {
+		s.declarator(d, typedef, body, declStart, declEnd)
+	}
+}
+
+// declarator adds the symbol declared by the declarator d (with any
+// leading type specifiers), if any.
+func (s *cScanner) declarator(d []cToken, typedef, body bool, declStart, declEnd int) {
+	var name *cToken
+	isFunc := false
+	depth := 0
+scan:
+	for i := 0; i < len(d); i++ {
+		t := d[i]
+		switch t.text {
+		case "(":
+			// function pointer: (*name)
+			if depth == 0 && i+2 < len(d) && d[i+1].text == "*" && d[i+2].isIdent() {
+				name, isFunc = &d[i+2], false
+				break scan
+			}
+			depth++
+			continue
+		case "{", "[":
+			depth++
+			continue
+		case ")", "}", "]":
+			depth--
+			continue
+		case "=":
+			if depth == 0 {
+				// ignore initializers
+				break scan
+			}
+		}
+		if depth > 0 || !t.isIdent() || cKeywords[t.text] {
+			continue
+		}
+		if i > 0 && (d[i-1].text == "struct" || d[i-1].text == "union" || d[i-1].text == "enum") {
+			continue
+		}
+		if i+1 < len(d) && d[i+1].text == "(" {
+			name, isFunc = &d[i], true
+			break
+		}
+		name = &d[i]
+	}
+	if name == nil {
+		return
+	}
+
+	kind := definfo.CVar
+	switch {
+	case typedef:
+		kind = definfo.CTypedef
+	case isFunc:
+		kind = definfo.CFunc
+	}
+	s.add(name.text, kind, name.start, name.end(), declStart, declEnd, body && isFunc)
+}
+
+// enumerators adds the enumerators of the enum whose body (starting
+// with "{") is toks.
+func (s *cScanner) enumerators(toks []cToken, declStart, declEnd int) {
+	depth := 0
+	expectName := true
+	for _, t := range toks {
+		switch t.text {
+		case "{", "(", "[":
+			depth++
+			if depth == 1 {
+				expectName = true
+			}
+			continue
+		case "}", ")", "]":
+			depth--
+			if depth == 0 {
+				return
+			}
+			continue
+		case ",":
+			if depth == 1 {
+				expectName = true
+			}
+			continue
+		}
+		if depth == 1 && expectName && t.isIdent() {
+			s.add(t.text, definfo.CEnumerator, t.start, t.end(), declStart, declEnd, false)
+		}
+		expectName = false
+	}
+}
+
+func splitCTokens(toks []cToken, sep string) [][]cToken {
+	var parts [][]cToken
+	start, depth := 0, 0
+	for i, t := range toks {
+		switch t.text {
+		case "(", "{", "[":
+			depth++
+		case ")", "}", "]":
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, toks[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, toks[start:])
+}
+
+// buildCSymbols collects the C symbols that C.name refs in the package
+// can refer to: those in the cgo preambles of its files and those in
+// Options.CSymbols. Definitions take precedence over declarations.
+func (g *grapher) buildCSymbols() {
+	g.cSymbols = make(map[string]*CSymbol)
+	var syms []*CSymbol
+	for _, f := range g.files {
+		syms = append(syms, g.preambleSymbols(f)...)
+	}
+	syms = append(syms, g.opt.CSymbols...)
+	for _, sym := range syms {
+		if prev, ok := g.cSymbols[sym.Name]; ok && (prev.Body || !sym.Body) {
+			continue
+		}
+		g.cSymbols[sym.Name] = sym
+	}
+}
+
+// cSymbolPrefix is the first element of the paths of the defs for C
+// symbols. It contains a "$" so that it can't collide with the path of
+// a Go def (e.g., a field C of a type named name).
+const cSymbolPrefix = "C$"
+
+// cSymbolKey returns the key of the def for the C symbol name.
+func (g *grapher) cSymbolKey(name string) *DefKey {
+	return &DefKey{PackageImportPath: g.typesPkg.Path(), Path: []string{cSymbolPrefix, name}}
+}
+
+// emitCSymbols emits defs (and refs at their declarations) for the C
+// symbols that the package can refer to.
+func (g *grapher) emitCSymbols() {
+	names := make([]string, 0, len(g.cSymbols))
+	for name := range g.cSymbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sym := g.cSymbols[name]
+		key := g.cSymbolKey(name)
+		g.output.Defs = append(g.output.Defs, &Def{
+			Name:   name,
+			DefKey: key,
+
+			File:      sym.File,
+			IdentSpan: sym.IdentSpan,
+			DeclSpan:  sym.DeclSpan,
+
+			HeaderSpan: sym.DeclSpan,
+			FullSpan:   sym.DeclSpan,
+
+			DefInfo: definfo.DefInfo{
+				PkgScope: true,
+				PkgName:  g.typesPkg.Name(),
+				Kind:     definfo.CSymbol,
+				CKind:    sym.Kind,
+			},
+		})
+		g.output.Refs = append(g.output.Refs, &Ref{
+			Unit:  g.typesPkg.Path(),
+			File:  sym.File,
+			Span:  sym.IdentSpan,
+			Def:   key,
+			IsDef: true,
+		})
+	}
+}
+
+// cRef returns a ref from sel (in C.sel) to the C symbol it refers to,
+// or nil if it is not a known C symbol.
+func (g *grapher) cRef(sel *ast.Ident) *Ref {
+	if _, ok := g.cSymbols[sel.Name]; !ok {
+		return nil
+	}
+	return &Ref{
+		Unit: g.typesPkg.Path(),
+		File: g.fset.Position(sel.Pos()).Filename,
+		Span: makeSpan(g.fset, sel),
+		Def:  g.cSymbolKey(sel.Name),
+	}
+}
+
+// isCPkg reports whether x refers to the "C" pseudo-package.
+func (g *grapher) isCPkg(x ast.Expr) bool {
+	ident, ok := x.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pn, ok := g.typesInfo.Uses[ident].(*types.PkgName)
+	return ok && pn.Imported().Path() == "C"
+}
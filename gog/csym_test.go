@@ -0,0 +1,148 @@
+package gog
+
+import (
+	"go/ast"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestScanCFile(t *testing.T) {
+	src := `#include <stdio.h>
+
+#define MAX_LEN 64
+#define SQUARE(x) \
+	((x) * (x))
+
+/* a comment with int fake(void); in it */
+typedef unsigned long size_type;
+typedef int (*callback)(void *ctx, int n);
+typedef struct point { int x, y; } point_t;
+
+struct node {
+	struct node *next;
+	char name[MAX_LEN];
+};
+
+enum color { RED, GREEN = 2, BLUE };
+
+extern int counter, *pcounter;
+static const char *greeting = "hi; int nope;";
+
+int add(int a, int b);
+
+static int add(int a, int b) {
+	int sum = a + b;
+	if (sum > 0) { return sum; }
+	return 0;
+}
+
+void (*handler)(int);
+`
+	var got []string
+	for _, sym := range ScanCFile("x.c", []byte(src)) {
+		ident := src[sym.IdentSpan[0]:sym.IdentSpan[1]]
+		s := sym.Kind + " " + sym.Name
+		if ident != sym.Name {
+			s += " (" + ident + ")"
+		}
+		if sym.Body {
+			s += " {}"
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"macro MAX_LEN",
+		"macro SQUARE",
+		"typedef size_type",
+		"typedef callback",
+		"struct struct_point (point)",
+		"typedef point_t",
+		"struct struct_node (node)",
+		"enum enum_color (color)",
+		"enumerator RED",
+		"enumerator GREEN",
+		"enumerator BLUE",
+		"var counter",
+		"var pcounter",
+		"var greeting",
+		"func add",
+		"func add {}",
+		"var handler",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCgoRefs(t *testing.T) {
+	src := `package foo
+
+// #include <stdlib.h>
+//
+// typedef struct { int n; } thing;
+//
+// static int twice(int n) { return 2 * n; }
+//
+// #define GREETING "hello, \
+// world"
+// static const char *farewell = "bye, \
+// world";
+// static int after;
+import "C"
+
+func f() {
+	var t C.thing
+	_ = C.twice(t.n)
+	_ = C.fromFile
+	_ = C.unknown
+	_ = C.malloc(1)
+}
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.go"})
+	pkgInfo := prog.Created[0]
+	opt := Options{CSymbols: ScanCFile("foo.c", []byte("int fromFile;\n"))}
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, opt)
+
+	var defs []string
+	for _, def := range output.Defs {
+		if def.Kind != definfo.CSymbol {
+			continue
+		}
+		var ident string
+		if def.File == "foo.go" {
+			ident = src[def.IdentSpan[0]:def.IdentSpan[1]]
+		}
+		defs = append(defs, def.CKind+" "+strings.Join(def.Path, "/")+" "+def.File+" "+ident)
+	}
+	sort.Strings(defs)
+	wantDefs := []string{
+		"func C$/twice foo.go twice",
+		"macro C$/GREETING foo.go GREETING",
+		"typedef C$/thing foo.go thing",
+		"var C$/after foo.go after",
+		"var C$/farewell foo.go farewell",
+		"var C$/fromFile foo.c ",
+	}
+	if !reflect.DeepEqual(defs, wantDefs) {
+		t.Errorf("got defs %q, want %q", defs, wantDefs)
+	}
+	preamble := preambleSource(prog.Fset, pkgInfo.Files[0].Decls[0].(*ast.GenDecl).Doc)
+	if blanked := blankCComments(preamble); strings.Count(blanked, "\n") != strings.Count(preamble, "\n") {
+		t.Errorf("blanking comments and literals changed the lines of the preamble\n%s\nto\n%s", preamble, blanked)
+	}
+
+	var refs []string
+	for _, ref := range output.Refs {
+		if len(ref.Def.Path) == 2 && ref.Def.Path[0] == cSymbolPrefix && !ref.IsDef {
+			refs = append(refs, src[ref.Span[0]:ref.Span[1]]+" -> "+strings.Join(ref.Def.Path, "/"))
+		}
+	}
+	wantRefs := []string{"thing -> C$/thing", "twice -> C$/twice", "fromFile -> C$/fromFile"}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Errorf("got refs %q, want %q", refs, wantRefs)
+	}
+}
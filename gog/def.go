@@ -28,6 +28,29 @@ type Def struct {
 	IdentSpan [2]uint32
 	DeclSpan  [2]uint32
 
+	// HeaderSpan is the span of the def's header: a func's signature,
+	// "type T struct" for a struct type, etc. For defs without a body,
+	// it is the whole declaration (without the doc comment).
+	HeaderSpan [2]uint32
+
+	// BodySpan is the span of a func's body, a struct type's field
+	// list, an interface type's method list, etc. It is zero if the
+	// def has no body.
+	BodySpan [2]uint32
+
+	// FullSpan is the span of the full declaration, including its doc
+	// comment and line comment and (if it is the only spec in the
+	// declaration) the "var", "const" or "type" keyword.
+	FullSpan [2]uint32
+
+	// IdentRange, DeclRange, etc., are the line/character ranges of
+	// the corresponding spans, if Options.PositionEncoding is set.
+	IdentRange  *definfo.Range `json:",omitempty"`
+	DeclRange   *definfo.Range `json:",omitempty"`
+	HeaderRange *definfo.Range `json:",omitempty"`
+	BodyRange   *definfo.Range `json:",omitempty"`
+	FullRange   *definfo.Range `json:",omitempty"`
+
 	definfo.DefInfo
 }
 
@@ -52,6 +75,8 @@ func (g *grapher) NewDef(obj types.Object, declNode ast.Node, declIdent *ast.Ide
 		}
 	}
 
+	file := g.fset.Position(declIdent.Pos()).Filename
+
 	switch obj := obj.(type) {
 	case *types.Func:
 		sig := obj.Type().(*types.Signature)
@@ -59,17 +84,47 @@ func (g *grapher) NewDef(obj types.Object, declNode ast.Node, declIdent *ast.Ide
 			// omit package path; just get receiver type name
 			si.Receiver = strings.Replace(recv.Type().String(), obj.Pkg().Path()+".", "", 1)
 		}
+		si.TestKind = testKind(obj, file)
+		si.Signature = g.signature(sig)
+	case *types.TypeName:
+		if key.PackageImportPath != "builtin" {
+			si.Fields, si.Methods = g.members(obj.Type(), declNode)
+		}
+		si.EnumValues = g.enums[obj]
+	case *types.Const:
+		si.ConstValue, si.ConstKind = constValue(obj)
+	case *types.Var:
+		if field, ok := declNode.(*ast.Field); ok && obj.IsField() {
+			si.Tag = fieldTag(field)
+			si.TagKeys = parseTag(si.Tag)
+		}
+		si.EmbedPatterns, si.EmbedFiles = g.embedPatterns(obj)
+	}
+
+	si.Deprecated = g.deprecated[obj]
+	if g.opt.Metrics {
+		si.Metrics = g.metrics(obj, declNode)
 	}
+	si.InitOrder = g.initOrder[obj]
+	if ep, ok := g.entryPoints[obj]; ok {
+		si.EntryPoint, si.EntryPointName = ep.kind, ep.name
+	}
+
+	header, body, full := g.declSpans(declNode)
 
 	return &Def{
 		Name: obj.Name(),
 
 		DefKey: key,
 
-		File:      g.fset.Position(declIdent.Pos()).Filename,
+		File:      file,
 		IdentSpan: makeSpan(g.fset, declIdent),
 		DeclSpan:  makeSpan(g.fset, declNode),
 
+		HeaderSpan: header,
+		BodySpan:   body,
+		FullSpan:   full,
+
 		DefInfo: si,
 	}
 }
@@ -84,9 +139,10 @@ func (g *grapher) NewPackageDef(pkgDir string, pkg *types.Package) *Def {
 		File: pkgDir,
 
 		DefInfo: definfo.DefInfo{
-			Exported: true,
-			PkgName:  pkg.Name(),
-			Kind:     definfo.Package,
+			Exported:   true,
+			PkgName:    pkg.Name(),
+			Kind:       definfo.Package,
+			Deprecated: g.pkgDeprecated,
 		},
 	}
 }
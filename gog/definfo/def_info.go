@@ -19,13 +19,122 @@ type DefInfo struct {
 	// def is not a struct field).
 	FieldOfStruct string `json:",omitempty"`
 
+	// ImportedPackage is the import path of the package that this def
+	// names, if it is an import name.
+	ImportedPackage string `json:",omitempty"`
+
 	// TypeString is a string describing this def's Go type.
 	TypeString string
 
 	// UnderlyingTypeString is the function or method signature, if this is a function or method.
 	UnderlyingTypeString string `json:",omitempty"`
 
+	// Tag is the tag of this def, if it is a struct field.
+	Tag string `json:",omitempty"`
+
+	// TagKeys are the keys of Tag, parsed in the conventional
+	// key:"value" format, in order.
+	TagKeys []TagKey `json:",omitempty"`
+
+	// EmbedPatterns are the //go:embed patterns of this def, if it is
+	// a var initialized with embedded files.
+	EmbedPatterns []string `json:",omitempty"`
+
+	// EmbedFiles are the files that EmbedPatterns match, relative to
+	// the package directory (with slash separators).
+	EmbedFiles []string `json:",omitempty"`
+
+	// Signature is the structured signature of this def, if it is a
+	// function or method.
+	Signature *Signature `json:",omitempty"`
+
+	// Fields are the fields of this def, if it is a struct type, in
+	// declaration order.
+	Fields []Member `json:",omitempty"`
+
+	// Methods are the explicit methods and embedded interfaces of
+	// this def, if it is an interface type, or the methods declared
+	// on it, if it is another named type. Both are in declaration
+	// order (with an interface's embedded interfaces among its
+	// methods as in the source).
+	Methods []Member `json:",omitempty"`
+
+	// ConstValue is the exact value of this def, if it is a const
+	// (e.g., "42", "\"foo\"", "1.5" or "1/3").
+	ConstValue string `json:",omitempty"`
+
+	// ConstKind is the kind of this def's value (Bool, String, Int,
+	// Float or Complex), if it is a const.
+	ConstKind string `json:",omitempty"`
+
+	// EnumValues are the consts of this def's type that are declared
+	// in const blocks using iota, if this def is a type, in
+	// declaration order.
+	EnumValues []EnumValue `json:",omitempty"`
+
 	// Kind is the kind of Go thing this def is: struct, interface, func,
 	// package, etc.
 	Kind string `json:",omitempty"`
+
+	// CKind is the kind of C declaration this def is (CFunc, etc.),
+	// if it is a CSymbol.
+	CKind string `json:",omitempty"`
+
+	// AsmDirective is the directive (TEXT, GLOBL or DATA) that
+	// defines this def, if it is an AsmSymbol.
+	AsmDirective string `json:",omitempty"`
+
+	// ProtoKind is the kind of .proto declaration (ProtoMessage,
+	// ProtoField, etc.) this def is, if it is a ProtoSymbol.
+	ProtoKind string `json:",omitempty"`
+
+	// ProtoName is the fully qualified .proto name of this def (e.g.,
+	// "foo.v1.Request.user_id"), if it is a ProtoSymbol.
+	ProtoName string `json:",omitempty"`
+
+	// TestKind is the kind of test function this def is (TestFunc,
+	// BenchmarkFunc, FuzzFunc or ExampleFunc), or the empty string
+	// if it is not a test function.
+	TestKind string `json:",omitempty"`
+
+	// LowConfidence is whether this def is in a file excluded from
+	// the package's build (e.g., by build constraints), so the
+	// information about it may be incomplete or wrong.
+	LowConfidence bool `json:",omitempty"`
+
+	// Generated is whether this def is in a generated file (one with
+	// a "// Code generated ... DO NOT EDIT." comment).
+	Generated bool `json:",omitempty"`
+
+	// InitOrder is the position (starting at 1) in the package's
+	// initialization order of the initializer of this package-level
+	// var, or of this init func (init funcs run after all vars are
+	// initialized, in the order they appear in the files). Vars
+	// initialized by the same multi-value expression share a position.
+	// It is 0 for all other defs (including those in test files).
+	InitOrder int `json:",omitempty"`
+
+	// EntryPoint is the kind of entry point (EntryMain, EntryInit,
+	// EntryHTTPHandler, EntryFlag or EntryCommand) this def is, or the
+	// empty string if it is not one.
+	EntryPoint string `json:",omitempty"`
+
+	// EntryPointName is the HTTP pattern that this handler is
+	// registered for, or the name of this flag or command, if known.
+	EntryPointName string `json:",omitempty"`
+
+	// Capabilities are the sensitive capabilities (CapabilityUnsafe,
+	// etc.) that the refs in this package-level def's declaration
+	// need, or (for a package) that those of all of its defs need, in
+	// sorted order. They are only recorded if requested.
+	Capabilities []string `json:",omitempty"`
+
+	// Metrics are the size and complexity metrics of this func,
+	// method or type, if requested.
+	Metrics *Metrics `json:",omitempty"`
+
+	// Deprecated is the message of the "Deprecated: " paragraph in
+	// this def's doc comment, or the empty string if this def is not
+	// deprecated.
+	Deprecated string `json:",omitempty"`
 }
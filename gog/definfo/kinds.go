@@ -9,15 +9,102 @@ const (
 	Type      = "type"
 	Interface = "interface"
 	Const     = "const"
+
+	// ImportName is the name given to an imported package in a
+	// file (f in `import f "fmt"`).
+	ImportName = "import"
+
+	// CSymbol is a C declaration that cgo code can refer to as
+	// C.name (see DefInfo.CKind).
+	CSymbol = "csymbol"
+
+	// AsmSymbol is a symbol defined in an assembly file that has no
+	// Go declaration (see DefInfo.AsmDirective).
+	AsmSymbol = "asm"
+
+	// ProtoSymbol is a declaration in a .proto file that Go defs were
+	// generated from (see DefInfo.ProtoKind).
+	ProtoSymbol = "proto"
+
+	// EmbedFile is a file that a //go:embed pattern matches.
+	EmbedFile = "embedfile"
 )
 
 var GeneralKindMap = map[string]string{
-	Package:   Package,
-	Field:     Field,
-	Func:      Func,
-	Method:    Func,
-	Type:      Type,
-	Var:       Var,
-	Const:     Const,
-	Interface: Type,
+	Package:    Package,
+	Field:      Field,
+	Func:       Func,
+	Method:     Func,
+	Type:       Type,
+	Var:        Var,
+	Const:      Const,
+	Interface:  Type,
+	ImportName: Package,
+	CSymbol:    CSymbol,
+	AsmSymbol:  AsmSymbol,
+
+	ProtoSymbol: ProtoSymbol,
+	EmbedFile:   EmbedFile,
 }
+
+// Kinds of C declarations (see DefInfo.CKind).
+const (
+	CFunc       = "func"
+	CTypedef    = "typedef"
+	CMacro      = "macro"
+	CVar        = "var"
+	CEnumerator = "enumerator"
+	CStruct     = "struct"
+	CUnion      = "union"
+	CEnum       = "enum"
+)
+
+// Kinds of .proto declarations (see DefInfo.ProtoKind).
+const (
+	ProtoMessage   = "message"
+	ProtoField     = "field"
+	ProtoOneof     = "oneof"
+	ProtoEnum      = "enum"
+	ProtoEnumValue = "enum value"
+	ProtoService   = "service"
+	ProtoRPC       = "rpc"
+)
+
+// Kinds of entry points (see DefInfo.EntryPoint).
+const (
+	// EntryMain is the main func of a main package.
+	EntryMain = "main"
+
+	// EntryInit is an init func.
+	EntryInit = "init"
+
+	// EntryHTTPHandler is a func (or ServeHTTP method) registered with
+	// http.Handle or http.HandleFunc (or the *http.ServeMux methods).
+	EntryHTTPHandler = "http-handler"
+
+	// EntryFlag is a var defined as a command-line flag with package
+	// flag.
+	EntryFlag = "flag"
+
+	// EntryCommand is a cobra or go-flags command (or its Run, RunE or
+	// Execute func).
+	EntryCommand = "command"
+)
+
+// Sensitive capabilities that code can need (see
+// DefInfo.Capabilities).
+const (
+	CapabilityUnsafe  = "unsafe"
+	CapabilityReflect = "reflect"
+	CapabilitySyscall = "syscall" // package syscall or golang.org/x/sys/...
+	CapabilityExec    = "exec"    // package os/exec
+	CapabilityCgo     = "cgo"     // C symbols
+)
+
+// Kinds of test functions (see DefInfo.TestKind).
+const (
+	TestFunc      = "test"
+	BenchmarkFunc = "benchmark"
+	FuzzFunc      = "fuzz"
+	ExampleFunc   = "example"
+)
@@ -0,0 +1,38 @@
+package definfo
+
+// Metrics are size and complexity metrics of a func, method or type
+// def, if requested. Only those that apply to the def's kind are set.
+type Metrics struct {
+	// Lines is the number of lines that a func's or method's
+	// declaration spans.
+	Lines int `json:",omitempty"`
+
+	// Statements is the number of statements in a func's or method's
+	// body (including those in func literals, but not counting
+	// blocks and case clauses).
+	Statements int `json:",omitempty"`
+
+	// Complexity is the cyclomatic complexity of a func or method: 1
+	// plus the number of if, for and range statements, non-default
+	// case and select clauses, and && and || operators.
+	Complexity int `json:",omitempty"`
+
+	// Nesting is the maximum depth to which a func's or method's
+	// if, for, range, switch and select statements are nested.
+	Nesting int `json:",omitempty"`
+
+	// Params is the number of parameters of a func or method (not
+	// counting the receiver).
+	Params int `json:",omitempty"`
+
+	// Returns is the number of return statements in a func's or
+	// method's body (not counting those in func literals).
+	Returns int `json:",omitempty"`
+
+	// Fields is the number of fields of a struct type.
+	Fields int `json:",omitempty"`
+
+	// Methods is the number of methods declared on a named type, or
+	// the number of methods of an interface type.
+	Methods int `json:",omitempty"`
+}
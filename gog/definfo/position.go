@@ -0,0 +1,15 @@
+package definfo
+
+// Position is a 0-based line and character (column) position in a
+// file. How characters are counted depends on the position encoding
+// (see gog.PositionEncodings).
+type Position struct {
+	Line      int
+	Character int
+}
+
+// Range is a range of positions in a file. End is exclusive.
+type Range struct {
+	Start Position
+	End   Position
+}
@@ -0,0 +1,91 @@
+package definfo
+
+// DefKey identifies a def by the import path of its package and its
+// path within that package (as gog.DefKey does).
+type DefKey struct {
+	PackageImportPath string
+	Path              []string
+}
+
+// VarInfo is a parameter, result, receiver or struct field.
+type VarInfo struct {
+	// Name is the name of the var (or the empty string if it is
+	// unnamed).
+	Name string `json:",omitempty"`
+
+	// Type is a string describing the var's type, with package
+	// paths in full (as in DefInfo.TypeString).
+	Type string
+
+	// TypeDef is the key of the named type that Type refers to, after
+	// removing any pointer, slice, array or channel type constructors
+	// (e.g., T for []*T). It is nil for unnamed types.
+	TypeDef *DefKey `json:",omitempty"`
+}
+
+// Signature describes a func or method signature.
+type Signature struct {
+	// Recv is the method receiver, or nil if this is not a method.
+	Recv *Receiver `json:",omitempty"`
+
+	Params  []VarInfo `json:",omitempty"`
+	Results []VarInfo `json:",omitempty"`
+
+	// Variadic is whether the last parameter is variadic (in which
+	// case its Type is a slice type).
+	Variadic bool `json:",omitempty"`
+}
+
+// Receiver is a method receiver.
+type Receiver struct {
+	VarInfo
+
+	// Pointer is whether the receiver is a pointer.
+	Pointer bool `json:",omitempty"`
+}
+
+// Member is a field or method of a struct or interface type.
+type Member struct {
+	VarInfo
+
+	// Def is the key of the field or method's def.
+	Def *DefKey `json:",omitempty"`
+
+	// Embedded is whether this is an embedded field (or an embedded
+	// interface, for interfaces).
+	Embedded bool `json:",omitempty"`
+
+	// Tag is the field's tag, if any.
+	Tag string `json:",omitempty"`
+}
+
+// EnumValue is a const declared (in a const block using iota) with a
+// given named type.
+type EnumValue struct {
+	Name string
+
+	// Value is the const's exact value (as in DefInfo.ConstValue).
+	Value string
+
+	// Def is the key of the const's def.
+	Def *DefKey `json:",omitempty"`
+}
+
+// TagKey is a key of a struct field tag (e.g., json in
+// `json:"id,omitempty"`).
+type TagKey struct {
+	Key string
+
+	// Value is the unparsed value of the key.
+	Value string
+
+	// Name is the name the field is serialized as (e.g., id), which is
+	// the first comma-separated element of Value for most keys and the
+	// "name=" option for protobuf. It is empty if Value does not
+	// specify a name.
+	Name string `json:",omitempty"`
+
+	// Options are the remaining comma-separated elements of Value
+	// (e.g., omitempty), or all of them for protobuf.
+	Options []string `json:",omitempty"`
+}
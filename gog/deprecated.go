@@ -0,0 +1,128 @@
+package gog
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Deprecations maps the paths (joined with "/") of the deprecated
+// package-level defs of a package, and of their fields and methods, to
+// their deprecation messages. The package itself has the empty path.
+type Deprecations map[string]string
+
+// FileDeprecations returns the deprecations declared in the doc
+// comments of files, which make up a single package.
+func FileDeprecations(fset *token.FileSet, files []*ast.File) Deprecations {
+	deps := make(Deprecations)
+	if f := packageDocFile(fset, files); f != nil {
+		if msg := deprecationMessage(f.Doc); msg != "" {
+			deps[""] = msg
+		}
+	}
+	walkDeclDocs(files, func(path []string, ident *ast.Ident, doc *ast.CommentGroup) {
+		if msg := deprecationMessage(doc); msg != "" {
+			deps[strings.Join(path, "/")] = msg
+		}
+	})
+	return deps
+}
+
+// deprecationMessage returns the text of the "Deprecated: " paragraph
+// of doc, if any.
+func deprecationMessage(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, para := range strings.Split(doc.Text(), "\n\n") {
+		para = strings.TrimSpace(para)
+		if strings.HasPrefix(para, "Deprecated: ") {
+			return strings.Join(strings.Fields(strings.TrimPrefix(para, "Deprecated: ")), " ")
+		}
+	}
+	return ""
+}
+
+// walkDeclDocs calls fn for each package-level declaration in files,
+// and for each field and method of the struct and interface types they
+// declare, with its path, name and doc comment (which may be nil).
+// Method paths are prefixed with their receiver type name, and field
+// paths with their struct type name.
+func walkDeclDocs(files []*ast.File, fn func(path []string, ident *ast.Ident, doc *ast.CommentGroup)) {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				path := []string{decl.Name.Name}
+				if decl.Recv != nil && len(decl.Recv.List) == 1 {
+					if recv, ok := derefNode(decl.Recv.List[0].Type).(*ast.Ident); ok {
+						path = []string{recv.Name, decl.Name.Name}
+					}
+				}
+				fn(path, decl.Name, decl.Doc)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						doc, _ := specDoc(decl, spec.Doc, spec.Comment)
+						for _, name := range spec.Names {
+							fn([]string{name.Name}, name, doc)
+						}
+					case *ast.TypeSpec:
+						doc, _ := specDoc(decl, spec.Doc, spec.Comment)
+						fn([]string{spec.Name.Name}, spec.Name, doc)
+						var fields *ast.FieldList
+						switch t := spec.Type.(type) {
+						case *ast.StructType:
+							fields = t.Fields
+						case *ast.InterfaceType:
+							fields = t.Methods
+						}
+						if fields == nil {
+							continue
+						}
+						for _, field := range fields.List {
+							doc, _ := fieldDoc(field)
+							for _, ident := range fieldIdents(field) {
+								fn([]string{spec.Name.Name, ident.Name}, ident, doc)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// buildDeprecations records the deprecation messages of the defs in
+// the package being graphed.
+func (g *grapher) buildDeprecations() {
+	g.deprecated = make(map[types.Object]string)
+	walkDeclDocs(g.files, func(path []string, ident *ast.Ident, doc *ast.CommentGroup) {
+		if msg := deprecationMessage(doc); msg != "" {
+			if obj := g.typesInfo.Defs[ident]; obj != nil {
+				g.deprecated[obj] = msg
+			}
+		}
+	})
+	if f := packageDocFile(g.fset, g.files); f != nil {
+		g.pkgDeprecated = deprecationMessage(f.Doc)
+	}
+}
+
+// deprecation returns the deprecation message of obj (whose DefKey is
+// key), or the empty string if it is not deprecated. Objects in other
+// packages are looked up in Options.DepDeprecations.
+func (g *grapher) deprecation(obj types.Object, key *DefKey) string {
+	if pn, ok := obj.(*types.PkgName); ok {
+		if pn.Imported() == g.typesPkg {
+			return g.pkgDeprecated
+		}
+		return g.opt.DepDeprecations[pn.Imported().Path()][""]
+	}
+	if obj.Pkg() == g.typesPkg {
+		return g.deprecated[obj]
+	}
+	return g.opt.DepDeprecations[key.PackageImportPath][strings.Join(key.Path, "/")]
+}
@@ -0,0 +1,298 @@
+package gog
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// Annotation is information about a span of a file that is neither a
+// def, a ref nor a doc, such as a compiler or tool directive.
+type Annotation struct {
+	Unit string
+	Type string
+	File string
+	Span [2]uint32
+
+	// Data is type-specific data (e.g., an EmbedData or
+	// GenerateData), encoded as JSON.
+	Data interface{} `json:",omitempty"`
+}
+
+// Types of annotations.
+const (
+	// AnnotationEmbed is a //go:embed pattern. Its Data is an
+	// EmbedData.
+	AnnotationEmbed = "go:embed"
+
+	// AnnotationGenerate is a //go:generate directive. Its Data is a
+	// GenerateData.
+	AnnotationGenerate = "go:generate"
+)
+
+// EmbedData lists the files that a //go:embed pattern matches.
+type EmbedData struct {
+	Pattern string
+
+	// Files are the matched files, relative to the package directory
+	// (with slash separators).
+	Files []string `json:",omitempty"`
+}
+
+// GenerateData is the command of a //go:generate directive.
+type GenerateData struct {
+	Command string
+}
+
+// directiveArgs returns the arguments of the directive in c (e.g.,
+// "//go:embed a b"), with the offset of each in c.Text, if c is that
+// directive.
+func directiveArgs(c *ast.Comment, directive string) (args []string, offsets []int, ok bool) {
+	prefix := "//" + directive
+	if !strings.HasPrefix(c.Text, prefix) {
+		return nil, nil, false
+	}
+	rest := c.Text[len(prefix):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return nil, nil, false
+	}
+	off := len(prefix)
+	for {
+		trimmed := strings.TrimLeft(rest, " \t")
+		off += len(rest) - len(trimmed)
+		rest = trimmed
+		if rest == "" {
+			return args, offsets, true
+		}
+		end := strings.IndexAny(rest, " \t")
+		if rest[0] == '"' || rest[0] == '`' {
+			// quoted argument (as allowed in //go:embed patterns)
+			if q := strings.IndexByte(rest[1:], rest[0]); q != -1 {
+				end = q + 2
+			}
+		}
+		if end == -1 {
+			end = len(rest)
+		}
+		args = append(args, rest[:end])
+		offsets = append(offsets, off)
+		off += end
+		rest = rest[end:]
+	}
+}
+
+// buildEmbeds records the //go:embed patterns of each var in the
+// package being graphed, and the files they match.
+func (g *grapher) buildEmbeds() {
+	g.embeds = make(map[types.Object][]string)
+	for _, f := range g.files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				doc := vs.Doc
+				if doc == nil && !gd.Lparen.IsValid() {
+					doc = gd.Doc
+				}
+				if doc == nil || len(vs.Names) != 1 {
+					continue
+				}
+				obj := g.typesInfo.Defs[vs.Names[0]]
+				if obj == nil {
+					continue
+				}
+				for _, c := range doc.List {
+					args, _, ok := directiveArgs(c, "go:embed")
+					if !ok {
+						continue
+					}
+					for _, arg := range args {
+						if p, err := strconv.Unquote(arg); err == nil {
+							arg = p
+						}
+						g.embeds[obj] = append(g.embeds[obj], arg)
+					}
+				}
+			}
+		}
+	}
+}
+
+// embedPatterns returns the //go:embed patterns of obj and the files
+// they match.
+func (g *grapher) embedPatterns(obj types.Object) (patterns, files []string) {
+	seen := make(map[string]bool)
+	for _, p := range g.embeds[obj] {
+		patterns = append(patterns, p)
+		for _, f := range matchEmbedPattern(p, g.opt.Files) {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	return patterns, files
+}
+
+// matchEmbedPattern returns the files (relative to the package
+// directory, with slash separators) that the //go:embed pattern
+// matches: those it matches directly, and those in the directories it
+// matches (excluding files whose names begin with "." or "_", unless
+// the pattern has the "all:" prefix).
+func matchEmbedPattern(pattern string, files []string) []string {
+	all := strings.HasPrefix(pattern, "all:")
+	pattern = strings.TrimPrefix(pattern, "all:")
+
+	var matches []string
+	for _, f := range files {
+		if ok, _ := path.Match(pattern, f); ok {
+			matches = append(matches, f)
+			continue
+		}
+		elems := strings.Split(f, "/")
+		for i := 1; i < len(elems); i++ {
+			if ok, _ := path.Match(pattern, strings.Join(elems[:i], "/")); !ok {
+				continue
+			}
+			hidden := false
+			for _, e := range elems[i:] {
+				hidden = hidden || strings.HasPrefix(e, ".") || strings.HasPrefix(e, "_")
+			}
+			if all || !hidden {
+				matches = append(matches, f)
+			}
+			break
+		}
+	}
+	return matches
+}
+
+// embedFilePrefix is the first element of the paths of the defs for
+// the files that //go:embed patterns match (followed by the elements of
+// the file's path relative to the package directory). It contains a
+// "$" so that it can't collide with the path of a Go def.
+const embedFilePrefix = "file$"
+
+// emitDirectives emits refs for //go:linkname directives, refs from
+// //go:embed patterns to defs for the files they match, and
+// annotations for //go:embed and //go:generate directives in files.
+func (g *grapher) emitDirectives(files []*ast.File) {
+	embedFileDefs := make(map[string]*DefKey)
+	for _, f := range files {
+		filename := g.fset.Position(f.Package).Filename
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if args, offsets, ok := directiveArgs(c, "go:linkname"); ok && len(args) >= 1 {
+					g.emitLinkname(c, filename, args, offsets)
+				}
+				if args, offsets, ok := directiveArgs(c, "go:embed"); ok {
+					start := g.fset.Position(c.Pos()).Offset
+					for i, arg := range args {
+						pattern := arg
+						if p, err := strconv.Unquote(arg); err == nil {
+							pattern = p
+						}
+						span := [2]uint32{uint32(start + offsets[i]), uint32(start + offsets[i] + len(arg))}
+						matches := matchEmbedPattern(pattern, g.opt.Files)
+						g.output.Annotations = append(g.output.Annotations, &Annotation{
+							Unit: g.typesPkg.Path(),
+							Type: AnnotationEmbed,
+							File: filename,
+							Span: span,
+							Data: EmbedData{Pattern: pattern, Files: matches},
+						})
+						for _, m := range matches {
+							key := embedFileDefs[m]
+							if key == nil {
+								key = g.emitEmbedFileDef(filepath.Dir(filename), m)
+								embedFileDefs[m] = key
+							}
+							g.output.Refs = append(g.output.Refs, &Ref{Unit: g.typesPkg.Path(), File: filename, Span: span, Def: key})
+						}
+					}
+				}
+				if _, _, ok := directiveArgs(c, "go:generate"); ok {
+					g.output.Annotations = append(g.output.Annotations, &Annotation{
+						Unit: g.typesPkg.Path(),
+						Type: AnnotationGenerate,
+						File: filename,
+						Span: makeSpan(g.fset, c),
+						Data: GenerateData{Command: strings.TrimSpace(strings.TrimPrefix(c.Text, "//go:generate"))},
+					})
+				}
+			}
+		}
+	}
+
+}
+
+// emitEmbedFileDef emits a def for the file name (relative to dir, the
+// package directory, with slash separators) that a //go:embed pattern
+// matches, and returns its key. No def is emitted when graphing
+// excluded files, since it is emitted along with the package's other
+// defs.
+func (g *grapher) emitEmbedFileDef(dir, name string) *DefKey {
+	key := &DefKey{PackageImportPath: g.typesPkg.Path(), Path: append([]string{embedFilePrefix}, strings.Split(name, "/")...)}
+	if !g.opt.Excluded {
+		g.output.Defs = append(g.output.Defs, &Def{
+			Name:   path.Base(name),
+			DefKey: key,
+			File:   filepath.Join(dir, filepath.FromSlash(name)),
+			DefInfo: definfo.DefInfo{
+				PkgScope: true,
+				PkgName:  g.typesPkg.Name(),
+				Kind:     definfo.EmbedFile,
+			},
+		})
+	}
+	return key
+}
+
+// emitLinkname emits refs for the //go:linkname directive c (in
+// filename), with arguments args: from its local name to the def in
+// the package being graphed and, if given, from its remote name
+// (importpath.name) to the def it is linked to.
+func (g *grapher) emitLinkname(c *ast.Comment, filename string, args []string, offsets []int) {
+	start := g.fset.Position(c.Pos()).Offset
+	span := func(i int) [2]uint32 {
+		return [2]uint32{uint32(start + offsets[i]), uint32(start + offsets[i] + len(args[i]))}
+	}
+
+	if obj := g.typesPkg.Scope().Lookup(args[0]); obj != nil {
+		key, _ := g.defInfo(obj)
+		g.output.Refs = append(g.output.Refs, &Ref{Unit: g.typesPkg.Path(), File: filename, Span: span(0), Def: key})
+	}
+	if len(args) >= 2 {
+		if key := linknameTarget(args[1]); key != nil {
+			g.output.Refs = append(g.output.Refs, &Ref{Unit: g.typesPkg.Path(), File: filename, Span: span(1), Def: key})
+		}
+	}
+}
+
+// linknameTarget returns the key of the def named by the remote name
+// of a //go:linkname directive: an import path followed by "." and a
+// name (e.g., "runtime.nanotime"), method (e.g., "sync.(*Mutex).Lock")
+// or field. It returns nil if name is malformed.
+func linknameTarget(name string) *DefKey {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot == -1 {
+		return nil
+	}
+	dot += slash + 1
+	pkg, rest := name[:dot], name[dot+1:]
+	if pkg == "" || rest == "" {
+		return nil
+	}
+	rest = strings.NewReplacer("(*", "", "(", "", ")", "").Replace(rest)
+	return &DefKey{PackageImportPath: pkg, Path: strings.Split(rest, ".")}
+}
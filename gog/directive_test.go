@@ -0,0 +1,98 @@
+package gog
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestDirectives(t *testing.T) {
+	src := `package foo
+
+import (
+	"embed"
+)
+
+//go:generate stringer -type=Color
+
+//go:embed static "a b.txt"
+//go:embed all:tmpl
+var content embed.FS
+
+//go:linkname now time.now
+func now() (int64, int32, int64)
+
+//go:linkname lock sync.(*Mutex).Lock
+func lock()
+`
+	files := []string{"foo.go", "a b.txt", "static/index.html", "static/.hidden", "tmpl/_base.tmpl", "tmpl/x/page.tmpl", "other.txt"}
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.go"})
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{Files: files})
+
+	var refs []string
+	for _, ref := range output.Refs {
+		line := src[strings.LastIndex(src[:ref.Span[0]], "\n")+1:]
+		if strings.HasPrefix(line, "//go:linkname") {
+			refs = append(refs, src[ref.Span[0]:ref.Span[1]]+" -> "+ref.Def.PackageImportPath+"#"+strings.Join(ref.Def.Path, "/"))
+		}
+	}
+	wantRefs := []string{"now -> foo#now", "time.now -> time#now", "lock -> foo#lock", "sync.(*Mutex).Lock -> sync#Mutex/Lock"}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Errorf("got linkname refs %q, want %q", refs, wantRefs)
+	}
+
+	var embedRefs []string
+	for _, ref := range output.Refs {
+		if len(ref.Def.Path) > 0 && ref.Def.Path[0] == embedFilePrefix {
+			embedRefs = append(embedRefs, src[ref.Span[0]:ref.Span[1]]+" -> "+strings.Join(ref.Def.Path[1:], "/"))
+		}
+	}
+	wantEmbedRefs := []string{`static -> static/index.html`, `"a b.txt" -> a b.txt`, `all:tmpl -> tmpl/_base.tmpl`, `all:tmpl -> tmpl/x/page.tmpl`}
+	if !reflect.DeepEqual(embedRefs, wantEmbedRefs) {
+		t.Errorf("got embed refs %q, want %q", embedRefs, wantEmbedRefs)
+	}
+	var embedFileDefs []string
+	for _, def := range output.Defs {
+		if def.Kind == definfo.EmbedFile {
+			embedFileDefs = append(embedFileDefs, def.Name+" "+filepath.ToSlash(def.File))
+		}
+	}
+	wantEmbedFileDefs := []string{"index.html static/index.html", "a b.txt a b.txt", "_base.tmpl tmpl/_base.tmpl", "page.tmpl tmpl/x/page.tmpl"}
+	if !reflect.DeepEqual(embedFileDefs, wantEmbedFileDefs) {
+		t.Errorf("got embedded file defs %q, want %q", embedFileDefs, wantEmbedFileDefs)
+	}
+
+	var anns []string
+	for _, a := range output.Annotations {
+		data, err := json.Marshal(a.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		anns = append(anns, a.Type+" "+src[a.Span[0]:a.Span[1]]+" "+string(data))
+	}
+	wantAnns := []string{
+		`go:generate //go:generate stringer -type=Color {"Command":"stringer -type=Color"}`,
+		`go:embed static {"Pattern":"static","Files":["static/index.html"]}`,
+		`go:embed "a b.txt" {"Pattern":"a b.txt","Files":["a b.txt"]}`,
+		`go:embed all:tmpl {"Pattern":"all:tmpl","Files":["tmpl/_base.tmpl","tmpl/x/page.tmpl"]}`,
+	}
+	if !reflect.DeepEqual(anns, wantAnns) {
+		t.Errorf("got annotations\n%s\nwant\n%s", strings.Join(anns, "\n"), strings.Join(wantAnns, "\n"))
+	}
+
+	for _, def := range output.Defs {
+		if def.Name != "content" {
+			continue
+		}
+		wantPatterns := []string{"static", "a b.txt", "all:tmpl"}
+		wantFiles := []string{"static/index.html", "a b.txt", "tmpl/_base.tmpl", "tmpl/x/page.tmpl"}
+		if !reflect.DeepEqual(def.EmbedPatterns, wantPatterns) || !reflect.DeepEqual(def.EmbedFiles, wantFiles) {
+			t.Errorf("got embed patterns %q and files %q, want %q and %q", def.EmbedPatterns, def.EmbedFiles, wantPatterns, wantFiles)
+		}
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"go/ast"
 	"go/doc"
+	"go/doc/comment"
 	"go/token"
+	"path/filepath"
+	"strings"
 
 	"go/types"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
 )
 
 type Doc struct {
@@ -16,8 +21,69 @@ type Doc struct {
 	Format string
 	Data   string
 
+	// Source is where the doc comment was found relative to the def
+	// it documents (one of the DocSource* constants), or empty for
+	// unattached comments.
+	Source string `json:",omitempty"`
+
 	File string    `json:",omitempty"`
 	Span [2]uint32 `json:",omitempty"`
+
+	// Range is the line/character range of Span, if
+	// Options.PositionEncoding is set.
+	Range *definfo.Range `json:",omitempty"`
+
+	// Generated is whether the doc is in a generated file (see
+	// IsGenerated).
+	Generated bool `json:",omitempty"`
+}
+
+const (
+	// DocSourceLeading is a doc comment immediately preceding the
+	// declaration.
+	DocSourceLeading = "leading"
+
+	// DocSourceTrailing is a line comment following the declaration
+	// on the same line.
+	DocSourceTrailing = "trailing"
+
+	// DocSourceGroup is the doc comment of the parenthesized
+	// const, var or type declaration enclosing the declaration.
+	DocSourceGroup = "group"
+)
+
+// DocFormatSynopsis is the format of the doc holding a package's
+// synopsis (the first sentence of its package doc, as plain text).
+const DocFormatSynopsis = "text/x-go-synopsis"
+
+// packageDocFile returns the file whose package comment is the
+// package's doc. As with godoc, a doc.go file takes precedence;
+// otherwise the first non-test file in filename order that has a
+// package comment is used (test files are only considered if no
+// other file has one). It returns nil if no file has a package
+// comment.
+func packageDocFile(fset *token.FileSet, files []*ast.File) *ast.File {
+	var best *ast.File
+	var bestName string
+	rank := func(name string) int {
+		switch {
+		case filepath.Base(name) == "doc.go":
+			return 0
+		case strings.HasSuffix(name, "_test.go"):
+			return 2
+		}
+		return 1
+	}
+	for _, f := range files {
+		if f.Doc == nil || f.Doc.Text() == "" {
+			continue
+		}
+		name := fset.Position(f.Package).Filename
+		if best == nil || rank(name) < rank(bestName) || (rank(name) == rank(bestName) && name < bestName) {
+			best, bestName = f, name
+		}
+	}
+	return best
 }
 
 func (g *grapher) emitDocs(files []*ast.File, typesPkg *types.Package, typesInfo *types.Info) []*Doc {
@@ -27,148 +93,165 @@ func (g *grapher) emitDocs(files []*ast.File, typesPkg *types.Package, typesInfo
 		objOf[g.fset.Position(ident.Pos())] = obj
 	}
 
-	// First we collect all of the Doc comments from the files,
-	// which will make up the Doc for the package. If more than
-	// one file has a doc associated with it, append them
-	// together.
-	pkgDoc := ""
-	for _, f := range files {
-		if f.Doc == nil {
-			continue
-		}
-		if pkgDoc == "" {
-			pkgDoc = f.Doc.Text()
-			continue
+	// The package doc is taken from a single file's package
+	// comment (see packageDocFile), not from all of them.
+	pkgPath := typesPkg.Path()
+	var pkgDocFile *ast.File
+	if !g.opt.Excluded {
+		pkgDocFile = packageDocFile(g.fset, files)
+	}
+	if pkgDocFile != nil {
+		pkgObj := types.NewPkgName(0, typesPkg, pkgPath, typesPkg)
+		filename := g.fset.Position(pkgDocFile.Name.Pos()).Filename
+		text := pkgDocFile.Doc.Text()
+		pkgDocs = append(pkgDocs, g.emitDoc(pkgObj, pkgDocFile.Doc, text, DocSourceLeading, filename, pkgPath)...)
+		if synopsis := new(doc.Package).Synopsis(text); synopsis != "" {
+			key, _ := g.defInfo(pkgObj)
+			pkgDocs = append(pkgDocs, &Doc{
+				DefKey: key,
+				Unit:   pkgPath,
+				Format: DocFormatSynopsis,
+				Data:   synopsis,
+				Source: DocSourceLeading,
+				File:   filename,
+				Span:   makeSpan(g.fset, pkgDocFile.Doc),
+			})
 		}
-		pkgDoc += "\n" + f.Doc.Text()
 	}
 
-	pkgPath := typesPkg.Path()
-	fileDocs := g.emitDoc(types.NewPkgName(0, typesPkg, pkgPath, typesPkg), nil, pkgDoc, "", pkgPath)
-	pkgDocs = append(pkgDocs, fileDocs...)
-
 	// We walk the AST for comments attached to nodes.
 	for _, f := range files {
 		filename := g.fset.Position(f.Name.Pos()).Filename
 		// docSeen is a map from the starting byte of a doc to
 		// an empty struct.
 		docSeen := make(map[token.Pos]struct{})
+		if f.Doc != nil {
+			// Package comments other than pkgDocFile's (and those of
+			// excluded files) are not emitted at all: they are not
+			// unattached comments.
+			docSeen[f.Doc.Pos()] = struct{}{}
+		}
+		emit := func(ident *ast.Ident, c *ast.CommentGroup, source string) {
+			obj := objOf[g.fset.Position(ident.Pos())]
+			if c == nil || obj == nil {
+				return
+			}
+			if docs := g.emitDoc(obj, c, c.Text(), source, filename, pkgPath); len(docs) > 0 {
+				docSeen[c.Pos()] = struct{}{}
+				pkgDocs = append(pkgDocs, docs...)
+			}
+		}
 		ast.Inspect(f, func(node ast.Node) bool {
 			switch n := node.(type) {
 			case *ast.Field:
-				if n.Doc == nil {
-					return true
-				}
-				for _, i := range n.Names {
-					if docs := g.emitDoc(objOf[g.fset.Position(i.Pos())], n.Doc, n.Doc.Text(), filename, pkgPath); len(docs) > 0 {
-						docSeen[n.Doc.Pos()] = struct{}{}
-						pkgDocs = append(pkgDocs, docs...)
-					}
+				// Struct fields and interface methods.
+				c, source := fieldDoc(n)
+				for _, i := range fieldIdents(n) {
+					emit(i, c, source)
 				}
 			case *ast.FuncDecl:
-				if n.Doc == nil || n.Name == nil {
+				if n.Name == nil {
 					return true
 				}
-				if docs := g.emitDoc(objOf[g.fset.Position(n.Name.Pos())], n.Doc, n.Doc.Text(), filename, pkgPath); len(docs) > 0 {
-					docSeen[n.Doc.Pos()] = struct{}{}
-					pkgDocs = append(pkgDocs, docs...)
-				}
+				emit(n.Name, n.Doc, DocSourceLeading)
 			case *ast.GenDecl:
 				for _, spec := range n.Specs {
 					switch spec := spec.(type) {
 					case *ast.ValueSpec:
+						c, source := specDoc(n, spec.Doc, spec.Comment)
 						for _, name := range spec.Names {
-							c := firstNonNil(spec.Doc, spec.Comment, n.Doc)
-							if docs := g.emitDoc(objOf[g.fset.Position(name.Pos())], c, c.Text(), filename, pkgPath); len(docs) > 0 {
-								docSeen[c.Pos()] = struct{}{}
-								pkgDocs = append(pkgDocs, docs...)
-							}
+							emit(name, c, source)
 						}
 					case *ast.TypeSpec:
-						c := firstNonNil(spec.Doc, spec.Comment, n.Doc)
-						if docs := g.emitDoc(objOf[g.fset.Position(spec.Name.Pos())], c, c.Text(), filename, pkgPath); len(docs) > 0 {
-							docSeen[c.Pos()] = struct{}{}
-							pkgDocs = append(pkgDocs, docs...)
-						}
+						c, source := specDoc(n, spec.Doc, spec.Comment)
+						emit(spec.Name, c, source)
 					}
 				}
 			case *ast.ImportSpec:
-				if n.Doc == nil || n.Name == nil {
+				if n.Name == nil {
 					return true
 				}
-				if docs := g.emitDoc(objOf[g.fset.Position(n.Name.Pos())], n.Doc, n.Doc.Text(), filename, pkgPath); len(docs) > 0 {
-					docSeen[n.Doc.Pos()] = struct{}{}
-					pkgDocs = append(pkgDocs, docs...)
-				}
-			case *ast.TypeSpec:
-				if n.Doc == nil || n.Name == nil {
-					return true
-				}
-				if docs := g.emitDoc(objOf[g.fset.Position(n.Name.Pos())], n.Doc, n.Doc.Text(), filename, pkgPath); len(docs) > 0 {
-					docSeen[n.Doc.Pos()] = struct{}{}
-					pkgDocs = append(pkgDocs, docs...)
-				}
-			case *ast.ValueSpec:
-				if n.Doc == nil {
-					return true
-				}
-				for _, i := range n.Names {
-					if docs := g.emitDoc(objOf[g.fset.Position(i.Pos())], n.Doc, n.Doc.Text(), filename, pkgPath); len(docs) > 0 {
-						docSeen[n.Doc.Pos()] = struct{}{}
-						pkgDocs = append(pkgDocs, docs...)
-					}
-				}
+				emit(n.Name, n.Doc, DocSourceLeading)
 			}
 			return true
 		})
 		// Add comments that haven't already been seen.
 		for _, c := range f.Comments {
-			if _, seen := docSeen[c.Pos()]; !seen {
-				commentDocs := g.emitDoc(nil, c, c.Text(), filename, pkgPath)
+			if _, seen := docSeen[c.Pos()]; seen {
+				continue
+			}
+			class := classifyComment(f, c)
+			if g.opt.OmitComments[class] {
+				continue
+			}
+			if class == "" {
+				commentDocs := g.emitDoc(nil, c, c.Text(), "", filename, pkgPath)
 				pkgDocs = append(pkgDocs, commentDocs...)
+				continue
 			}
+			pkgDocs = append(pkgDocs, &Doc{
+				Unit:   pkgPath,
+				Format: CommentFormat(class),
+				Data:   commentData(c, class),
+				File:   filename,
+				Span:   makeSpan(g.fset, c),
+			})
 		}
 	}
 	return pkgDocs
 }
 
-func firstNonNil(comments ...*ast.CommentGroup) *ast.CommentGroup {
-	for _, c := range comments {
-		if c != nil {
-			return c
-		}
+// fieldDoc returns the doc comment of a struct field or interface
+// method, falling back to its trailing line comment.
+func fieldDoc(f *ast.Field) (*ast.CommentGroup, string) {
+	if f.Doc != nil {
+		return f.Doc, DocSourceLeading
+	}
+	if f.Comment != nil {
+		return f.Comment, DocSourceTrailing
+	}
+	return nil, ""
+}
+
+// fieldIdents returns the identifiers declared by f. For an embedded
+// field, that is the (unqualified) name of its type.
+func fieldIdents(f *ast.Field) []*ast.Ident {
+	if len(f.Names) > 0 {
+		return f.Names
+	}
+	if ident, ok := derefNode(f.Type).(*ast.Ident); ok {
+		return []*ast.Ident{ident}
 	}
 	return nil
 }
 
-func (g *grapher) emitDoc(obj types.Object, dc *ast.CommentGroup, docstring, filename, pkgPath string) (docs []*Doc) {
+// specDoc returns the doc comment of a const, var or type spec in
+// decl. A spec's own doc comment (or decl's, if decl is not
+// parenthesized) takes precedence over its trailing line comment,
+// which in turn takes precedence over the doc comment of the
+// enclosing parenthesized group.
+func specDoc(decl *ast.GenDecl, doc, comment *ast.CommentGroup) (*ast.CommentGroup, string) {
+	if doc != nil {
+		return doc, DocSourceLeading
+	}
+	if !decl.Lparen.IsValid() && decl.Doc != nil {
+		return decl.Doc, DocSourceLeading
+	}
+	if comment != nil {
+		return comment, DocSourceTrailing
+	}
+	if decl.Doc != nil {
+		return decl.Doc, DocSourceGroup
+	}
+	return nil, ""
+}
+
+func (g *grapher) emitDoc(obj types.Object, dc *ast.CommentGroup, docstring, source, filename, pkgPath string) (docs []*Doc) {
 	if docstring == "" {
 		return
 	}
 	if obj == nil {
-		var htmlBuf bytes.Buffer
-		doc.ToHTML(&htmlBuf, docstring, nil)
-		var span [2]uint32
-		if dc != nil {
-			span = makeSpan(g.fset, dc)
-		}
-		docs = append(docs, &Doc{
-			DefKey: nil,
-			Unit:   pkgPath,
-			Format: "text/html",
-			Data:   htmlBuf.String(),
-			File:   filename,
-			Span:   span,
-		})
-		docs = append(docs, &Doc{
-			DefKey: nil,
-			Unit:   pkgPath,
-			Format: "text/plain",
-			Data:   docstring,
-			File:   filename,
-			Span:   span,
-		})
-		return
+		return g.formatDoc(nil, dc, docstring, source, filename, pkgPath)
 	}
 
 	if g.seenDocObjs == nil {
@@ -189,19 +272,25 @@ func (g *grapher) emitDoc(obj types.Object, dc *ast.CommentGroup, docstring, fil
 	}
 	g.seenDocKeys[key.String()] = struct{}{}
 
-	var htmlBuf bytes.Buffer
-	doc.ToHTML(&htmlBuf, docstring, nil)
+	return g.formatDoc(key, dc, docstring, source, filename, pkgPath)
+}
 
+// formatDoc renders docstring in each of the enabled doc formats.
+func (g *grapher) formatDoc(key *DefKey, dc *ast.CommentGroup, docstring, source, filename, pkgPath string) (docs []*Doc) {
 	var span [2]uint32
 	if dc != nil {
 		span = makeSpan(g.fset, dc)
 	}
 
+	var htmlBuf bytes.Buffer
+	doc.ToHTML(&htmlBuf, docstring, nil)
+
 	docs = append(docs, &Doc{
 		DefKey: key,
 		Unit:   pkgPath,
 		Format: "text/html",
 		Data:   htmlBuf.String(),
+		Source: source,
 		File:   filename,
 		Span:   span,
 	})
@@ -210,8 +299,62 @@ func (g *grapher) emitDoc(obj types.Object, dc *ast.CommentGroup, docstring, fil
 		Unit:   pkgPath,
 		Format: "text/plain",
 		Data:   docstring,
+		Source: source,
 		File:   filename,
 		Span:   span,
 	})
+	if g.opt.MarkdownDocs {
+		docs = append(docs, &Doc{
+			DefKey: key,
+			Unit:   pkgPath,
+			Format: "text/markdown",
+			Data:   g.toMarkdown(docstring),
+			Source: source,
+			File:   filename,
+			Span:   span,
+		})
+	}
 	return
 }
+
+// toMarkdown renders docstring as Markdown. Doc links ("[Name]",
+// "[pkg.Name]") are resolved against the package being graphed and
+// its imports.
+func (g *grapher) toMarkdown(docstring string) string {
+	p := comment.Parser{
+		LookupPackage: g.lookupDocPackage,
+		LookupSym:     g.lookupDocSym,
+	}
+	var pr comment.Printer
+	return string(pr.Markdown(p.Parse(docstring)))
+}
+
+// lookupDocPackage resolves the package name in a doc link such as
+// "[http.Client]" to the import path of a package imported by the
+// package being graphed.
+func (g *grapher) lookupDocPackage(name string) (importPath string, ok bool) {
+	if name == g.typesPkg.Name() {
+		return g.typesPkg.Path(), true
+	}
+	for _, imp := range g.typesPkg.Imports() {
+		if imp.Name() == name {
+			return imp.Path(), true
+		}
+	}
+	return "", false
+}
+
+// lookupDocSym reports whether recv.name (or just name, if recv is
+// empty) is declared in the package being graphed.
+func (g *grapher) lookupDocSym(recv, name string) bool {
+	scope := g.typesPkg.Scope()
+	if recv == "" {
+		return scope.Lookup(name) != nil
+	}
+	tn, ok := scope.Lookup(recv).(*types.TypeName)
+	if !ok {
+		return false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(tn.Type(), true, g.typesPkg, name)
+	return obj != nil
+}
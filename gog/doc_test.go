@@ -0,0 +1,361 @@
+package gog
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// docsOf returns the docs in output for the def with the given path,
+// keyed by format.
+func docsOf(output *Output, path string) map[string]string {
+	docs := make(map[string]string)
+	for _, d := range output.Docs {
+		if d.DefKey != nil && strings.Join(d.DefKey.Path, "/") == path {
+			docs[d.Format] = d.Data
+		}
+	}
+	return docs
+}
+
+func TestMarkdownDocs(t *testing.T) {
+	src := `package foo
+
+import "strings"
+
+// F does things.
+//
+// # Usage
+//
+// Call it with a [T]:
+//
+//	F(T{})
+//
+// See also:
+//   - [T.M]
+//   - [strings.Index]
+func F(t T) {}
+
+type T struct{}
+
+func (T) M() {}
+
+var _ = strings.Index
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
+	if md, present := docsOf(output, "F")["text/markdown"]; present {
+		t.Errorf("got markdown doc %q, want none when MarkdownDocs is not set", md)
+	}
+
+	output = Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true, MarkdownDocs: true})
+	md := docsOf(output, "F")["text/markdown"]
+	for _, want := range []string{
+		"### Usage",
+		"[T](#T)",
+		"\tF(T{})",
+		"  - [T.M](#T.M)",
+		"  - [strings.Index](/strings#Index)",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown doc does not contain %q\n### Got:\n%s", want, md)
+		}
+	}
+	for _, doc := range output.Docs {
+		if doc.Format == "text/markdown" && doc.Source != DocSourceLeading {
+			t.Errorf("got markdown doc source %q, want %q", doc.Source, DocSourceLeading)
+		}
+	}
+}
+
+func TestTrailingDocs(t *testing.T) {
+	src := `package foo
+
+type T struct {
+	// A is documented.
+	A int
+	B int // B is documented.
+	C int
+	D // D is embedded.
+}
+
+type D struct{}
+
+type I interface {
+	M() // M is documented.
+}
+
+// X is documented.
+var X = 1 // trailing
+
+const (
+	// Y is documented.
+	Y = iota
+	Z // Z is documented.
+)
+
+// W is documented.
+var (
+	W int
+)
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
+
+	want := map[string]struct{ text, source string }{
+		"T/A": {"A is documented.\n", DocSourceLeading},
+		"T/B": {"B is documented.\n", DocSourceTrailing},
+		"T/D": {"D is embedded.\n", DocSourceTrailing},
+		"I/M": {"M is documented.\n", DocSourceTrailing},
+		"X":   {"X is documented.\n", DocSourceLeading},
+		"Y":   {"Y is documented.\n", DocSourceLeading},
+		"Z":   {"Z is documented.\n", DocSourceTrailing},
+		"W":   {"W is documented.\n", DocSourceGroup},
+	}
+	got := make(map[string]*Doc)
+	for _, d := range output.Docs {
+		if d.Format != "text/plain" {
+			continue
+		}
+		if d.DefKey == nil {
+			if d.Data != "trailing\n" {
+				t.Errorf("got unexpected unattached doc %q", d.Data)
+			}
+			continue
+		}
+		got[strings.Join(d.DefKey.Path, "/")] = d
+	}
+	for path, w := range want {
+		d := got[path]
+		if d == nil {
+			t.Errorf("%s: no doc", path)
+			continue
+		}
+		if d.Data != w.text || d.Source != w.source {
+			t.Errorf("%s: got doc %q (source %q), want %q (source %q)", path, d.Data, d.Source, w.text, w.source)
+		}
+	}
+	if d := got["T/C"]; d != nil {
+		t.Errorf("T/C: got doc %q, want none", d.Data)
+	}
+}
+
+func TestUnattachedCommentClasses(t *testing.T) {
+	src := `// Copyright 2016 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+// Package foo does things.
+package foo
+
+//go:generate stringer -type=T
+
+func f() {
+	// BUG(sqs): f does nothing.
+
+	// TODO: make f do something.
+
+	// just a comment
+}
+
+//go:noinline
+//go:nosplit
+func g() {}
+`
+	want := map[string]string{
+		CommentFormat(CommentLicense):         "Copyright 2016 The Authors. All rights reserved.\nUse of this source code is governed by a BSD-style\nlicense that can be found in the LICENSE file.\n",
+		CommentFormat(CommentBuildConstraint): "//go:build linux\n// +build linux\n",
+		CommentFormat(CommentGenerate):        "//go:generate stringer -type=T\n",
+		CommentFormat(CommentDirective):       "//go:noinline\n//go:nosplit\n",
+		"text/plain":                          "just a comment\n",
+	}
+	notes := []string{"BUG(sqs): f does nothing.\n", "TODO: make f do something.\n"}
+
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
+	got := make(map[string][]string)
+	for _, d := range output.Docs {
+		if d.DefKey == nil {
+			got[d.Format] = append(got[d.Format], d.Data)
+		}
+	}
+	for format, data := range want {
+		if len(got[format]) != 1 || got[format][0] != data {
+			t.Errorf("%s: got %q, want %q", format, got[format], data)
+		}
+	}
+	if !reflect.DeepEqual(got[CommentFormat(CommentNote)], notes) {
+		t.Errorf("notes: got %q, want %q", got[CommentFormat(CommentNote)], notes)
+	}
+
+	omit := make(map[string]bool)
+	for _, class := range CommentClasses {
+		omit[class] = true
+	}
+	output = Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true, OmitComments: omit})
+	for _, d := range output.Docs {
+		if d.DefKey == nil && d.Format != "text/plain" && d.Format != "text/html" {
+			t.Errorf("got %s doc %q, want it omitted", d.Format, d.Data)
+		}
+	}
+}
+
+func TestPackageDoc(t *testing.T) {
+	sources := []string{
+		"// Package foo is in a.go.\npackage foo\n",
+		"// Package foo does things. It is in doc.go.\n//\n// More about foo.\npackage foo\n",
+		"package foo\n",
+	}
+	names := []string{"a.go", "doc.go", "b.go"}
+	prog := createPkg(t, "foo", sources, names)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
+
+	docs := make(map[string]*Doc)
+	for _, d := range output.Docs {
+		if d.DefKey != nil && len(d.DefKey.Path) == 0 {
+			docs[d.Format] = d
+		}
+	}
+	want := map[string]string{
+		"text/plain":      "Package foo does things. It is in doc.go.\n\nMore about foo.\n",
+		DocFormatSynopsis: "Package foo does things.",
+	}
+	for format, data := range want {
+		d := docs[format]
+		if d == nil {
+			t.Errorf("%s: no package doc", format)
+			continue
+		}
+		if d.Data != data {
+			t.Errorf("%s: got %q, want %q", format, d.Data, data)
+		}
+		if wantSpan := [2]uint32{0, uint32(strings.Index(sources[1], "\npackage"))}; d.File != "doc.go" || d.Span != wantSpan {
+			t.Errorf("%s: got %s:%v, want doc.go:%v", format, d.File, d.Span, wantSpan)
+		}
+	}
+
+	// a.go's package comment is not emitted as an unattached comment
+	for _, d := range output.Docs {
+		if d.File == "a.go" {
+			t.Errorf("got doc %q (for %v) in a.go", d.Data, d.DefKey)
+		}
+	}
+}
+
+func TestExamples(t *testing.T) {
+	sources := []string{
+		"package foo\n\nfunc F() {}\n\ntype T int\n\nfunc (T) M() {}\n",
+		`package foo
+
+import "fmt"
+
+func Example() {}
+
+// This example shows F.
+func ExampleF() {
+	F()
+	fmt.Println("hi")
+	// Output: hi
+}
+
+func ExampleT_M_other() {
+	var t T
+	t.M()
+}
+
+func ExampleT_Z() {}
+`,
+	}
+	prog := createPkg(t, "foo", sources, []string{"foo.go", "foo_test.go"})
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
+
+	got := make(map[string]Example)
+	for _, d := range output.Docs {
+		if d.Format != DocFormatExample {
+			continue
+		}
+		var ex Example
+		if err := json.Unmarshal([]byte(d.Data), &ex); err != nil {
+			t.Fatal(err)
+		}
+		if d.File != "foo_test.go" {
+			t.Errorf("%s: got file %q, want foo_test.go", ex.Name, d.File)
+		}
+		got[ex.Name+" -> "+strings.Join(d.DefKey.Path, "/")] = ex
+	}
+	want := map[string]Example{
+		" -> ":             {Name: ""},
+		"F -> F":           {Name: "F", Doc: "This example shows F.\n", Code: "F()\nfmt.Println(\"hi\")\n", Output: "hi\n"},
+		"T_M_other -> T/M": {Name: "T_M_other", Suffix: "other", Code: "var t T\nt.M()\n"},
+		"T_Z -> ":          {Name: "T_Z"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got examples %+v, want %+v", got, want)
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	sources := []string{
+		`package foo
+
+import "strings"
+
+// F does things.
+//
+// Deprecated: Use G instead,
+// which is better.
+func F() {}
+
+// G does things.
+func G() {
+	F()
+	_ = strings.Title("a")
+	_ = strings.ToUpper("a")
+}
+
+type T struct {
+	// Deprecated: don't use.
+	X int
+}
+`,
+	}
+	prog := createPkg(t, "foo", sources, nil)
+	pkgInfo := prog.Created[0]
+	opt := Options{DepDeprecations: map[string]Deprecations{"strings": {"Title": "Use cases instead."}}}
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, opt)
+
+	deprecated := make(map[string]string)
+	for _, def := range output.Defs {
+		if def.Deprecated != "" {
+			deprecated[strings.Join(def.Path, "/")] = def.Deprecated
+		}
+	}
+	want := map[string]string{"F": "Use G instead, which is better.", "T/X": "don't use."}
+	if !reflect.DeepEqual(deprecated, want) {
+		t.Errorf("got deprecated defs %v, want %v", deprecated, want)
+	}
+	if deps := FileDeprecations(prog.Fset, pkgInfo.Files); !reflect.DeepEqual(map[string]string(deps), want) {
+		t.Errorf("got file deprecations %v, want %v", deps, want)
+	}
+
+	var deprecatedRefs []string
+	for _, ref := range output.Refs {
+		if ref.Deprecated {
+			deprecatedRefs = append(deprecatedRefs, ref.Def.String())
+		}
+	}
+	if want := []string{"foo#F", "strings#Title"}; !reflect.DeepEqual(deprecatedRefs, want) {
+		t.Errorf("got refs to deprecated defs %v, want %v", deprecatedRefs, want)
+	}
+}
@@ -0,0 +1,203 @@
+package gog
+
+import (
+	"go/types"
+)
+
+// embeddingChain returns the embedded fields traversed (in order) to
+// reach the field or method that sel selects, or nil if it is not
+// promoted. As a side effect, it records the struct type of each of
+// the fields in selRecvs, for building their paths.
+func (g *grapher) embeddingChain(sel *types.Selection) []*types.Var {
+	index := sel.Index()
+	if len(index) < 2 {
+		return nil
+	}
+	recv := sel.Recv()
+	chain := make([]*types.Var, 0, len(index)-1)
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := recv.Underlying().(*types.Pointer); ok {
+			recv = ptr.Elem()
+		}
+		st, ok := recv.Underlying().(*types.Struct)
+		if !ok {
+			return nil
+		}
+		f := st.Field(i)
+		g.selRecvs[f] = recv
+		chain = append(chain, f)
+		recv = f.Type()
+	}
+	return chain
+}
+
+// emitPromotions relates each struct type declared at package level to
+// the fields and methods that it promotes from its embedded fields
+// (RelationPromotes).
+func (g *grapher) emitPromotions() []*Relation {
+	var rels []*Relation
+	scope := g.typesPkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			continue
+		}
+		from, _ := g.defInfo(tn)
+
+		promoted := func(obj types.Object) {
+			to, _ := g.defInfo(obj)
+			rels = append(rels, &Relation{Kind: RelationPromotes, From: from, To: to})
+		}
+
+		mset := types.NewMethodSet(types.NewPointer(named))
+		for i := 0; i < mset.Len(); i++ {
+			sel := mset.At(i)
+			if len(sel.Index()) < 2 {
+				continue
+			}
+			m := sel.Obj()
+			g.selRecvs[m] = m.Type().(*types.Signature).Recv().Type()
+			promoted(m)
+		}
+
+		for _, f := range g.embeddedFields(named) {
+			obj, index, _ := types.LookupFieldOrMethod(named, true, f.Pkg(), f.Name())
+			if obj == types.Object(f) && len(index) >= 2 {
+				promoted(f)
+			}
+		}
+	}
+	return rels
+}
+
+// emitOverrides relates each method declared on a package-level struct
+// type to the methods of the same name of its embedded fields, which
+// it shadows (RelationOverrides).
+func (g *grapher) emitOverrides() []*Relation {
+	var rels []*Relation
+	scope := g.typesPkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			m := named.Method(i)
+			var from *DefKey
+			for j := 0; j < st.NumFields(); j++ {
+				f := st.Field(j)
+				if !f.Anonymous() {
+					continue
+				}
+				shadowed, ok := lookupMethod(f.Type(), m.Pkg(), m.Name())
+				if !ok {
+					continue
+				}
+				if from == nil {
+					from, _ = g.defInfo(m)
+				}
+				g.selRecvs[shadowed] = shadowed.Type().(*types.Signature).Recv().Type()
+				to, _ := g.defInfo(shadowed)
+				rels = append(rels, &Relation{Kind: RelationOverrides, From: from, To: to})
+			}
+		}
+	}
+	return rels
+}
+
+// lookupMethod returns the method named name in the method set of *t
+// (or of t, if t is an interface type).
+func lookupMethod(t types.Type, pkg *types.Package, name string) (*types.Func, bool) {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, pkg, name)
+	m, ok := obj.(*types.Func)
+	return m, ok
+}
+
+// emitInterfaceEmbeds relates each package-level interface type to the
+// named interfaces that it embeds (RelationEmbeds).
+func (g *grapher) emitInterfaceEmbeds() []*Relation {
+	var rels []*Relation
+	scope := g.typesPkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		iface, ok := tn.Type().Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		from, _ := g.defInfo(tn)
+		for i := 0; i < iface.NumEmbeddeds(); i++ {
+			named, ok := iface.EmbeddedType(i).(*types.Named)
+			if !ok {
+				continue
+			}
+			to, _ := g.defInfo(named.Obj())
+			rels = append(rels, &Relation{Kind: RelationEmbeds, From: from, To: to})
+		}
+	}
+	return rels
+}
+
+// embeddedFields returns the fields of the structs that are embedded
+// (directly or indirectly) in t, recording the struct type of each in
+// selRecvs.
+func (g *grapher) embeddedFields(t *types.Named) []*types.Var {
+	var fields []*types.Var
+	seen := map[*types.Named]bool{t: true}
+	queue := []*types.Named{t}
+	for len(queue) > 0 {
+		st, _ := queue[0].Underlying().(*types.Struct)
+		queue = queue[1:]
+		if st == nil {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			if !f.Anonymous() {
+				continue
+			}
+			named, ok := derefType(f.Type()).(*types.Named)
+			if !ok || seen[named] {
+				continue
+			}
+			seen[named] = true
+			queue = append(queue, named)
+			if est, ok := named.Underlying().(*types.Struct); ok {
+				for j := 0; j < est.NumFields(); j++ {
+					g.selRecvs[est.Field(j)] = named
+					fields = append(fields, est.Field(j))
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// PromotingTypes returns the struct types that promote the field or
+// method def, according to the RelationPromotes relations in rels.
+func PromotingTypes(rels []*Relation, def *DefKey) []*DefKey {
+	var types []*DefKey
+	for _, rel := range rels {
+		if rel.Kind == RelationPromotes && rel.To.String() == def.String() {
+			types = append(types, rel.From)
+		}
+	}
+	return types
+}
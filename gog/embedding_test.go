@@ -0,0 +1,144 @@
+package gog
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPromotion(t *testing.T) {
+	src := `package foo
+
+import "bytes"
+
+type A struct{ X int }
+
+func (A) M() {}
+
+type B struct{ *A }
+
+type C struct {
+	B
+	bytes.Buffer
+}
+
+func f(c C) {
+	_ = c.X
+	c.M()
+	c.B.M()
+}
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	keyStr := func(k *DefKey) string {
+		return k.PackageImportPath + "#" + strings.Join(k.Path, "/")
+	}
+
+	var refs []string
+	for _, ref := range output.Refs {
+		if !ref.Implicit && ref.Via == nil {
+			continue
+		}
+		s := src[ref.Span[0]:ref.Span[1]] + " -> " + keyStr(ref.Def)
+		if ref.Implicit {
+			s += " (implicit)"
+		}
+		for _, via := range ref.Via {
+			s += " via " + keyStr(via)
+		}
+		refs = append(refs, s)
+	}
+	wantRefs := []string{
+		"X -> foo#A/X via foo#C/B via foo#B/A",
+		"X -> foo#C/B (implicit)",
+		"X -> foo#B/A (implicit)",
+		"M -> foo#A/M via foo#C/B via foo#B/A",
+		"M -> foo#C/B (implicit)",
+		"M -> foo#B/A (implicit)",
+		"M -> foo#A/M via foo#B/A",
+		"M -> foo#B/A (implicit)",
+	}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Errorf("got refs\n%s\nwant\n%s", strings.Join(refs, "\n"), strings.Join(wantRefs, "\n"))
+	}
+
+	var rels []string
+	for _, rel := range output.Relations {
+		if rel.Kind == RelationPromotes && !strings.HasPrefix(rel.To.PackageImportPath, "bytes") {
+			rels = append(rels, keyStr(rel.From)+" promotes "+keyStr(rel.To))
+		}
+	}
+	sort.Strings(rels)
+	wantRels := []string{
+		"foo#B promotes foo#A/M",
+		"foo#B promotes foo#A/X",
+		"foo#C promotes foo#A/M",
+		"foo#C promotes foo#A/X",
+		"foo#C promotes foo#B/A",
+	}
+	if !reflect.DeepEqual(rels, wantRels) {
+		t.Errorf("got relations\n%s\nwant\n%s", strings.Join(rels, "\n"), strings.Join(wantRels, "\n"))
+	}
+
+	var types []string
+	for _, k := range PromotingTypes(output.Relations, &DefKey{PackageImportPath: "bytes", Path: []string{"Buffer", "Len"}}) {
+		types = append(types, keyStr(k))
+	}
+	if want := []string{"foo#C"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("got types promoting bytes.Buffer.Len %q, want %q", types, want)
+	}
+}
+
+func TestOverridesAndEmbeds(t *testing.T) {
+	src := `package foo
+
+import "io"
+
+type A struct{}
+
+func (A) M()       {}
+func (*A) N()      {}
+func (A) Close() error { return nil }
+
+type B struct {
+	*A
+	io.ReadCloser
+}
+
+func (B) M() {}
+
+func (*B) Close() error { return nil }
+
+type I interface {
+	io.Reader
+	J
+	P()
+}
+
+type J interface{ Q() }
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	var rels []string
+	for _, rel := range output.Relations {
+		if rel.Kind == RelationOverrides || rel.Kind == RelationEmbeds {
+			rels = append(rels, strings.Join(rel.From.Path, "/")+" "+rel.Kind+" "+rel.To.PackageImportPath+"#"+strings.Join(rel.To.Path, "/"))
+		}
+	}
+	sort.Strings(rels)
+	want := []string{
+		"B/Close overrides foo#A/Close",
+		"B/Close overrides io#Closer/Close",
+		"B/M overrides foo#A/M",
+		"I embeds foo#J",
+		"I embeds io#Reader",
+	}
+	if !reflect.DeepEqual(rels, want) {
+		t.Errorf("got relations\n%s\nwant\n%s", strings.Join(rels, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,262 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// entryPoint is how a def is invoked from outside the code that
+// declares it (see definfo.EntryMain, etc.).
+type entryPoint struct {
+	kind string
+	name string // the HTTP pattern, flag name or command name, if any
+}
+
+const (
+	cobraPkgPath    = "github.com/spf13/cobra"
+	goFlagsPkgPath  = "github.com/jessevdk/go-flags"
+	goFlagsPkgPath2 = "gopkg.in/jessevdk/go-flags.v1"
+)
+
+// flagFuncs are the funcs in package flag (and methods of *flag.FlagSet)
+// that define a flag, and whether they take a pointer to the flag's var
+// as their first argument (instead of returning one).
+var flagFuncs = map[string]bool{
+	"Bool": false, "Duration": false, "Float64": false, "Int": false, "Int64": false,
+	"String": false, "Uint": false, "Uint64": false,
+	"BoolVar": true, "DurationVar": true, "Float64Var": true, "IntVar": true, "Int64Var": true,
+	"StringVar": true, "UintVar": true, "Uint64Var": true, "TextVar": true, "Var": true,
+}
+
+// buildEntryPoints finds the defs in the package being graphed that
+// are entry points: main and init funcs, HTTP handlers registered with
+// http.Handle or http.HandleFunc (or the *http.ServeMux methods), vars
+// defined as flags with package flag, and cobra and go-flags commands.
+// They are found by the well-known stdlib and library defs that the
+// package's code refers to, not by running it.
+func (g *grapher) buildEntryPoints() {
+	g.entryPoints = make(map[types.Object]entryPoint)
+	for _, f := range g.files {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil {
+				obj := g.typesInfo.Defs[fd.Name]
+				if fd.Name.Name == "main" && g.typesPkg.Name() == "main" && obj != nil {
+					g.entryPoints[obj] = entryPoint{kind: definfo.EntryMain}
+				} else if fd.Name.Name == "init" && obj != nil {
+					g.entryPoints[obj] = entryPoint{kind: definfo.EntryInit}
+				}
+			}
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ValueSpec:
+				for i, name := range n.Names {
+					if i < len(n.Values) && len(n.Names) == len(n.Values) {
+						g.entryPointVar(g.typesInfo.Defs[name], n.Values[i])
+					}
+				}
+			case *ast.AssignStmt:
+				if len(n.Lhs) == len(n.Rhs) {
+					for i, lhs := range n.Lhs {
+						if ident, ok := lhs.(*ast.Ident); ok {
+							obj := g.typesInfo.Defs[ident]
+							if obj == nil {
+								obj = g.typesInfo.Uses[ident]
+							}
+							g.entryPointVar(obj, n.Rhs[i])
+						}
+					}
+				}
+			case *ast.CallExpr:
+				g.entryPointCall(n)
+			}
+			return true
+		})
+	}
+}
+
+// entryPointVar tags the var obj if its value x defines a flag (as in
+// flag.String(...), for a package-level var) or a cobra command (as in
+// &cobra.Command{...}).
+func (g *grapher) entryPointVar(obj types.Object, x ast.Expr) {
+	if obj == nil {
+		return
+	}
+	x = unparen(x)
+	if u, ok := x.(*ast.UnaryExpr); ok {
+		x = unparen(u.X)
+	}
+	switch x := x.(type) {
+	case *ast.CallExpr:
+		if fn := g.callee(x); fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == "flag" {
+			if byPtr, ok := flagFuncs[fn.Name()]; ok && !byPtr && isPkgLevel(obj) {
+				g.entryPoints[obj] = entryPoint{kind: definfo.EntryFlag, name: stringArg(x, 0)}
+			}
+		}
+	case *ast.CompositeLit:
+		if tn, ok := g.exprObj(x.Type).(*types.TypeName); ok && isNamed(tn.Type(), cobraPkgPath, "Command") {
+			ep := entryPoint{kind: definfo.EntryCommand}
+			var runs []*types.Func
+			for _, elt := range x.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				key, ok := kv.Key.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				switch key.Name {
+				case "Use":
+					// the command name is the first word of Use
+					if lit, ok := kv.Value.(*ast.BasicLit); ok {
+						use, _ := strconv.Unquote(lit.Value)
+						if fields := strings.Fields(use); len(fields) > 0 {
+							ep.name = fields[0]
+						}
+					}
+				case "Run", "RunE":
+					if fn, ok := g.exprObj(kv.Value).(*types.Func); ok {
+						runs = append(runs, fn)
+					}
+				}
+			}
+			g.entryPoints[obj] = ep
+			for _, fn := range runs {
+				g.entryPoints[fn] = ep
+			}
+		}
+	}
+}
+
+// entryPointCall tags the defs that call registers as entry points: HTTP
+// handlers, package-level flag vars defined by pointer (as in
+// flag.StringVar(&v, ...)) and go-flags commands.
+func (g *grapher) entryPointCall(call *ast.CallExpr) {
+	fn := g.callee(call)
+	if fn == nil || fn.Pkg() == nil {
+		return
+	}
+	switch path := fn.Pkg().Path(); {
+	case path == "net/http" && (fn.Name() == "Handle" || fn.Name() == "HandleFunc") && len(call.Args) == 2:
+		ep := entryPoint{kind: definfo.EntryHTTPHandler, name: stringArg(call, 0)}
+		switch obj := g.exprObj(call.Args[1]).(type) {
+		case *types.Func:
+			g.entryPoints[obj] = ep
+		case *types.Var, *types.TypeName:
+			if m := g.localMethod(obj.Type(), "ServeHTTP"); m != nil {
+				g.entryPoints[m] = ep
+			}
+		}
+
+	case path == "flag":
+		if byPtr := flagFuncs[fn.Name()]; byPtr && len(call.Args) > 0 {
+			if v, ok := g.exprObj(call.Args[0]).(*types.Var); ok && isPkgLevel(v) {
+				g.entryPoints[v] = entryPoint{kind: definfo.EntryFlag, name: stringArg(call, 1)}
+			}
+		}
+
+	case (path == goFlagsPkgPath || path == goFlagsPkgPath2) && fn.Name() == "AddCommand" && len(call.Args) == 4:
+		ep := entryPoint{kind: definfo.EntryCommand, name: stringArg(call, 0)}
+		if v, ok := g.exprObj(call.Args[3]).(*types.Var); ok {
+			g.entryPoints[v] = ep
+			if m := g.localMethod(v.Type(), "Execute"); m != nil {
+				g.entryPoints[m] = ep
+			}
+		}
+	}
+}
+
+// callee returns the func or method that call calls, or nil if it is
+// not a static call (e.g., it is a conversion or a call of a func
+// value).
+func (g *grapher) callee(call *ast.CallExpr) *types.Func {
+	var ident *ast.Ident
+	switch fun := unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := g.typesInfo.Uses[ident].(*types.Func)
+	return fn
+}
+
+// exprObj returns the object that x denotes, looking through parens,
+// "&", conversions and composite literals (whose type name it
+// returns), or nil if there is none.
+func (g *grapher) exprObj(x ast.Expr) types.Object {
+	switch x := unparen(x).(type) {
+	case *ast.Ident:
+		return g.typesInfo.Uses[x]
+	case *ast.SelectorExpr:
+		return g.typesInfo.Uses[x.Sel]
+	case *ast.UnaryExpr:
+		return g.exprObj(x.X)
+	case *ast.CallExpr:
+		if _, ok := g.exprObj(x.Fun).(*types.TypeName); ok && len(x.Args) == 1 {
+			return g.exprObj(x.Args[0])
+		}
+	case *ast.CompositeLit:
+		if x.Type != nil {
+			return g.exprObj(x.Type)
+		}
+	}
+	return nil
+}
+
+// localMethod returns the method named name of typ (or of the type typ
+// points to), if typ is a named type declared in the package being
+// graphed.
+func (g *grapher) localMethod(typ types.Type, name string) *types.Func {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() != g.typesPkg {
+		return nil
+	}
+	m, _, _ := types.LookupFieldOrMethod(named, true, g.typesPkg, name)
+	fn, _ := m.(*types.Func)
+	return fn
+}
+
+// isNamed reports whether typ (or the type it points to) is the named
+// type pkgPath.name.
+func isNamed(typ types.Type, pkgPath, name string) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkgPath && named.Obj().Name() == name
+}
+
+// stringArg returns the value of call's i'th argument if it is a string
+// literal, or else the empty string.
+func stringArg(call *ast.CallExpr, i int) string {
+	if i >= len(call.Args) {
+		return ""
+	}
+	if lit, ok := unparen(call.Args[i]).(*ast.BasicLit); ok {
+		s, _ := strconv.Unquote(lit.Value)
+		return s
+	}
+	return ""
+}
+
+func unparen(x ast.Expr) ast.Expr {
+	for {
+		p, ok := x.(*ast.ParenExpr)
+		if !ok {
+			return x
+		}
+		x = p.X
+	}
+}
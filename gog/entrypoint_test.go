@@ -0,0 +1,170 @@
+package gog
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// fakeImporter type-checks the packages it imports from source.
+type fakeImporter struct {
+	fset *token.FileSet
+	srcs map[string]string
+	pkgs map[string]*types.Package
+}
+
+func (imp *fakeImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := imp.pkgs[path]; ok {
+		return pkg, nil
+	}
+	src, ok := imp.srcs[path]
+	if !ok {
+		return nil, fmt.Errorf("no package %s", path)
+	}
+	f, err := parser.ParseFile(imp.fset, path+"/x.go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: imp}
+	pkg, err := conf.Check(path, imp.fset, []*ast.File{f}, nil)
+	if err != nil {
+		return nil, err
+	}
+	imp.pkgs[path] = pkg
+	return pkg, nil
+}
+
+func TestEntryPoints(t *testing.T) {
+	src := `package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/spf13/cobra"
+)
+
+var addr = flag.String("addr", ":8080", "listen address")
+
+var verbose int
+
+var fs flag.FlagSet
+
+var dryRun = fs.Bool("n", false, "dry run")
+
+func init() { flag.IntVar(&verbose, "v", 0, "verbosity") }
+
+func main() {
+	http.HandleFunc("/", index)
+	http.Handle("/api", &api{})
+	mux := new(http.ServeMux)
+	mux.HandleFunc("/health", http.HandlerFunc(health))
+
+	rootCmd := &cobra.Command{Use: "serve [flags]", Run: serve}
+	_ = rootCmd
+
+	var parser flags.Parser
+	parser.AddCommand("graph", "graph a package", "", &graphCmd)
+
+	// local flag vars aren't entry points
+	local := flag.String("local", "", "")
+	var n int
+	flag.IntVar(&n, "n", 0, "")
+	_ = local
+}
+
+func index(w http.ResponseWriter, r *http.Request)  {}
+func health(w http.ResponseWriter, r *http.Request) {}
+
+type api struct{}
+
+func (*api) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+func serve(cmd *cobra.Command, args []string) {}
+
+type GraphCmd struct{}
+
+func (c *GraphCmd) Execute(args []string) error { return nil }
+
+var graphCmd GraphCmd
+`
+	fset := token.NewFileSet()
+	imp := &fakeImporter{fset: fset, pkgs: map[string]*types.Package{}, srcs: map[string]string{
+		"flag": `package flag
+type FlagSet struct{}
+func (*FlagSet) Bool(name string, value bool, usage string) *bool { return nil }
+func String(name, value, usage string) *string { return nil }
+func IntVar(p *int, name string, value int, usage string) {}
+`,
+		"net/http": `package http
+type ResponseWriter interface{}
+type Request struct{}
+type Handler interface{ ServeHTTP(ResponseWriter, *Request) }
+type HandlerFunc func(ResponseWriter, *Request)
+func (f HandlerFunc) ServeHTTP(w ResponseWriter, r *Request) { f(w, r) }
+type ServeMux struct{}
+func (*ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Request)) {}
+func Handle(pattern string, handler Handler) {}
+func HandleFunc(pattern string, handler func(ResponseWriter, *Request)) {}
+`,
+		"github.com/spf13/cobra": `package cobra
+type Command struct {
+	Use string
+	Run func(cmd *Command, args []string)
+}
+`,
+		"github.com/jessevdk/go-flags": `package flags
+type Command struct{}
+type Parser struct{ *Command }
+func (*Command) AddCommand(command, short, long string, data interface{}) (*Command, error) { return nil, nil }
+`,
+	}}
+	f, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: imp}
+	info := &types.Info{
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	pkg, err := conf.Check("main", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := Graph(fset, []*ast.File{f}, pkg, info, Options{})
+
+	var got []string
+	for _, def := range output.Defs {
+		if def.EntryPoint != "" {
+			got = append(got, fmt.Sprintf("%s %s %q", def.Name, def.EntryPoint, def.EntryPointName))
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		`Execute command "graph"`,
+		`ServeHTTP http-handler "/api"`,
+		`addr flag "addr"`,
+		`dryRun flag "n"`,
+		`graphCmd command "graph"`,
+		`health http-handler "/health"`,
+		`index http-handler "/"`,
+		`init init ""`,
+		`main main ""`,
+		`rootCmd command "serve"`,
+		`serve command "serve"`,
+		`verbose flag "v"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got entry points\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,239 @@
+package gog
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/types"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DocFormatExample is the format of docs that hold a testable example
+// (an ExampleXxx function in a _test.go file). Their Data is the JSON
+// encoding of an Example.
+const DocFormatExample = "application/x-go-example+json"
+
+// Example is a testable example attached to the def it exemplifies.
+type Example struct {
+	// Name is the name of the example function, without the
+	// "Example" prefix (e.g., "T_Method_suffix").
+	Name string
+
+	// Suffix is the example's suffix (e.g., "suffix" for
+	// ExampleT_Method_suffix), if any.
+	Suffix string `json:",omitempty"`
+
+	// Doc is the example function's doc comment.
+	Doc string `json:",omitempty"`
+
+	// Code is the source of the example's body.
+	Code string
+
+	// Output is the expected output, from the "// Output:" or
+	// "// Unordered output:" comment.
+	Output string `json:",omitempty"`
+
+	// Unordered is whether the output is unordered.
+	Unordered bool `json:",omitempty"`
+
+	// EmptyOutput is whether the example expects empty output (as
+	// opposed to not being run at all).
+	EmptyOutput bool `json:",omitempty"`
+}
+
+// emitExamples emits a doc for each example in the _test.go files in
+// files. Examples are attached to the def they name, following the
+// same naming conventions as go/doc; examples that don't name any def
+// are attached to the package.
+func (g *grapher) emitExamples(files []*ast.File) []*Doc {
+	var testFiles []*ast.File
+	for _, f := range files {
+		if strings.HasSuffix(g.fset.Position(f.Package).Filename, "_test.go") {
+			testFiles = append(testFiles, f)
+		}
+	}
+	if len(testFiles) == 0 {
+		return nil
+	}
+
+	targetPkg := g.testedPkg()
+	ids := exampleTargets(targetPkg)
+
+	var docs []*Doc
+	for _, ex := range doc.Examples(testFiles...) {
+		key := &DefKey{PackageImportPath: targetPkg.Path(), Path: []string{}}
+		e := Example{Name: ex.Name}
+		for i := len(ex.Name); i >= 0; i = strings.LastIndexByte(ex.Name[:i], '_') {
+			prefix, suffix, ok := splitExampleName(ex.Name, i)
+			if !ok {
+				continue
+			}
+			if obj, ok := ids[prefix]; ok {
+				if obj != nil {
+					key = g.testedDefKey(obj, targetPkg)
+				}
+				e.Suffix = suffix
+				break
+			}
+		}
+
+		e.Doc = ex.Doc
+		e.Code = g.exampleCode(ex)
+		e.Output = ex.Output
+		e.Unordered = ex.Unordered
+		e.EmptyOutput = ex.EmptyOutput
+		data, err := json.Marshal(e)
+		if err != nil {
+			log.Printf("Warning: skipping example %s: %s", ex.Name, err)
+			continue
+		}
+
+		decl := g.exampleDecl(testFiles, ex)
+		if decl == nil {
+			continue
+		}
+		docs = append(docs, &Doc{
+			DefKey: key,
+			Unit:   g.typesPkg.Path(),
+			Format: DocFormatExample,
+			Data:   string(data),
+			File:   g.fset.Position(decl.Pos()).Filename,
+			Span:   makeSpan(g.fset, decl),
+		})
+	}
+	return docs
+}
+
+// testedPkg returns the package whose defs the tests and examples in
+// the package being graphed exercise. For an external test package
+// ("foo_test"), that is the package under test.
+func (g *grapher) testedPkg() *types.Package {
+	if !strings.HasSuffix(g.typesPkg.Name(), "_test") {
+		return g.typesPkg
+	}
+	path := strings.TrimSuffix(g.typesPkg.Path(), "_test")
+	for _, imp := range g.typesPkg.Imports() {
+		if imp.Path() == path {
+			return imp
+		}
+	}
+	return g.typesPkg
+}
+
+// exampleTargets maps the identifiers that example names may refer to
+// ("F", "T", "T_M") to the objects they denote in pkg. The empty
+// identifier maps to nil, for the package itself.
+func exampleTargets(pkg *types.Package) map[string]types.Object {
+	ids := map[string]types.Object{"": nil}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Func:
+			ids[name] = obj
+		case *types.TypeName:
+			ids[name] = obj
+			named, ok := obj.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			for i := 0; i < named.NumMethods(); i++ {
+				if m := named.Method(i); m.Exported() {
+					ids[name+"_"+m.Name()] = m
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// testedDefKey returns the DefKey of obj, an example target or test
+// subject in pkg (see testedPkg). For an external test package, obj's
+// receiver (if any) is taken from its signature, since g.path only
+// knows the receivers of methods selected in the files being graphed.
+func (g *grapher) testedDefKey(obj types.Object, pkg *types.Package) *DefKey {
+	if pkg == g.typesPkg {
+		key, _ := g.defInfo(obj)
+		return key
+	}
+	// Example targets and test subjects are package-level defs (or
+	// their methods), whose paths are their names.
+	path := []string{obj.Name()}
+	if f, ok := obj.(*types.Func); ok {
+		if recv := f.Type().(*types.Signature).Recv(); recv != nil {
+			if named, ok := derefType(recv.Type()).(*types.Named); ok {
+				path = []string{named.Obj().Name(), obj.Name()}
+			}
+		}
+	}
+	return &DefKey{PackageImportPath: pkg.Path(), Path: path}
+}
+
+// splitExampleName attempts to split example name s at index i, and
+// reports whether that produces a valid split (as in go/doc). The
+// suffix may be absent. Otherwise, it must start with a lower-case
+// letter and be preceded by '_'.
+func splitExampleName(s string, i int) (prefix, suffix string, ok bool) {
+	if i == len(s) {
+		return s, "", true
+	}
+	if i == len(s)-1 {
+		return "", "", false
+	}
+	prefix, suffix = s[:i], s[i+1:]
+	r, _ := utf8.DecodeRuneInString(suffix)
+	return prefix, suffix, unicode.IsLower(r)
+}
+
+var exampleOutputRx = regexp.MustCompile(`(?i)//[[:space:]]*(unordered )?output:`)
+
+// exampleCode returns the source of ex's code. If the code is a
+// function body, the enclosing braces and the output comment are
+// removed and it is unindented (as godoc does).
+func (g *grapher) exampleCode(ex *doc.Example) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, &printer.CommentedNode{Node: ex.Code, Comments: ex.Comments})
+	code := buf.String()
+	if _, ok := ex.Code.(*ast.BlockStmt); ok {
+		code = strings.TrimSpace(code)
+		code = strings.TrimSuffix(strings.TrimPrefix(code, "{"), "}")
+		if loc := exampleOutputRx.FindStringIndex(code); loc != nil {
+			code = code[:loc[0]]
+		}
+		code = strings.TrimSpace(code)
+		if code == "" {
+			return ""
+		}
+		lines := strings.Split(code, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimPrefix(l, "\t")
+		}
+		code = strings.Join(lines, "\n") + "\n"
+	}
+	return code
+}
+
+// exampleDecl returns the declaration of the example function for ex.
+func (g *grapher) exampleDecl(files []*ast.File, ex *doc.Example) *ast.FuncDecl {
+	name := "Example" + ex.Name
+	for _, f := range files {
+		if !tokenFileContainsPos(g.fset.File(f.Pos()), ex.Code.Pos()) {
+			continue
+		}
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == name {
+				return fd
+			}
+		}
+	}
+	return nil
+}
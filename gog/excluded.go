@@ -0,0 +1,55 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// resolveBySyntax resolves ident, which the type checker could not
+// resolve, by syntax and scope only: to the def of the declaration that
+// the parser resolved it to, or else to the package-level or universe
+// object with its name. It returns nil if ident can't be resolved
+// (e.g., because it selects a field or method of an expression whose
+// type is unknown).
+func (g *grapher) resolveBySyntax(ident *ast.Ident) types.Object {
+	if g.selNames[ident] {
+		return nil
+	}
+	if ident.Obj != nil {
+		var declIdent *ast.Ident
+		switch decl := ident.Obj.Decl.(type) {
+		case *ast.Field:
+			declIdent = findIdent(decl.Names, ident.Name)
+		case *ast.ValueSpec:
+			declIdent = findIdent(decl.Names, ident.Name)
+		case *ast.TypeSpec:
+			declIdent = decl.Name
+		case *ast.FuncDecl:
+			declIdent = decl.Name
+		case *ast.AssignStmt:
+			for _, lhs := range decl.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && id.Name == ident.Name {
+					declIdent = id
+				}
+			}
+		}
+		if declIdent != nil {
+			if obj := g.typesInfo.Defs[declIdent]; obj != nil {
+				return obj
+			}
+		}
+	}
+	if obj := g.typesPkg.Scope().Lookup(ident.Name); obj != nil {
+		return obj
+	}
+	return types.Universe.Lookup(ident.Name)
+}
+
+func findIdent(idents []*ast.Ident, name string) *ast.Ident {
+	for _, id := range idents {
+		if id.Name == name {
+			return id
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package gog
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExcluded(t *testing.T) {
+	pkgSrc := `package foo
+
+func init() {}
+
+func Open() int { x := 0; return x }
+
+type T struct{ X int }
+`
+	excludedSrc := `//go:build ignore
+
+// Package foo is not documented here.
+package foo
+
+import "unknown/dep"
+
+func Open() int { x := 1; return helper(dep.Value) + x }
+
+func helper(v T) int {
+	n := v.X
+	return n + dep.F(v).Y
+}
+`
+	fset := token.NewFileSet()
+	pkgFile, err := parser.ParseFile(fset, "foo.go", pkgSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excludedFile, err := parser.ParseFile(fset, "foo_other.go", excludedSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Error: func(error) {}}
+	info := &types.Info{
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	pkg, _ := conf.Check("foo", fset, []*ast.File{pkgFile, excludedFile}, info)
+	opt := Options{Excluded: true, IncludeDocs: true, PackageFiles: []*ast.File{pkgFile}}
+	output := Graph(fset, []*ast.File{excludedFile}, pkg, info, opt)
+
+	// The defs in excluded files are prefixed with their filename, so
+	// that they don't collide with the package's defs (such as Open's
+	// x) or those of other excluded files.
+	var defs []string
+	for _, def := range output.Defs {
+		if !def.LowConfidence {
+			t.Errorf("def %s is not marked as low confidence", def.Name)
+		}
+		defs = append(defs, strings.Join(def.Path, "/"))
+	}
+	sort.Strings(defs)
+	want := []string{"foo_other.go/Open/x/1", "foo_other.go/helper", "foo_other.go/helper/n", "foo_other.go/helper/v"}
+	if !reflect.DeepEqual(defs, want) {
+		t.Errorf("got defs %q, want %q", defs, want)
+	}
+
+	var refs []string
+	for _, ref := range output.Refs {
+		if !ref.LowConfidence {
+			t.Errorf("ref at %v is not marked as low confidence", ref.Span)
+		}
+		if ref.Def.PackageImportPath == "foo" && len(ref.Def.Path) > 0 {
+			refs = append(refs, excludedSrc[ref.Span[0]:ref.Span[1]]+" -> "+strings.Join(ref.Def.Path, "/"))
+		}
+	}
+	sort.Strings(refs)
+	want = []string{
+		"Open -> Open",
+		"T -> T",
+		"X -> T/X",
+		"helper -> foo_other.go/helper",
+		"helper -> foo_other.go/helper",
+		"n -> foo_other.go/helper/n",
+		"n -> foo_other.go/helper/n",
+		"v -> foo_other.go/helper/v",
+		"v -> foo_other.go/helper/v",
+		"v -> foo_other.go/helper/v",
+		"x -> foo_other.go/Open/x/1",
+		"x -> foo_other.go/Open/x/1",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs %q, want %q", refs, want)
+	}
+
+	if len(output.Relations) != 0 {
+		t.Errorf("got relations %v, want none", output.Relations)
+	}
+	for _, doc := range output.Docs {
+		if strings.Contains(doc.Data, "not documented") {
+			t.Errorf("got the excluded file's package comment as doc %+v", doc)
+		}
+	}
+
+}
@@ -0,0 +1,106 @@
+package gog
+
+import (
+	"go/ast"
+	"regexp"
+)
+
+// generatedRx matches the standard comment that marks a file as
+// generated (see https://golang.org/s/generatedcode).
+var generatedRx = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// IsGenerated reports whether f has the standard "// Code generated
+// ... DO NOT EDIT." comment before its package clause, as *.pb.go
+// files, stringer output, mocks, etc., do.
+func IsGenerated(f *ast.File) bool {
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if generatedRx.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markGenerated flags the defs, refs and docs in generated files (see
+// IsGenerated), or removes them from the output if
+// Options.OmitGenerated is set, along with the annotations in those
+// files and the relations from their defs. Refs to defs in generated
+// files from other files are kept.
+func (g *grapher) markGenerated(files []*ast.File) {
+	// Files are keyed by their unadjusted names (and the names that
+	// their //line directives give, which their defs, refs and docs
+	// have), not by the //line-adjusted name of their package clause.
+	generated := make(map[string]bool)
+	for _, f := range files {
+		if tf := g.fset.File(f.Pos()); tf != nil && IsGenerated(f) {
+			for name := range fileNames(tf) {
+				generated[name] = true
+			}
+		}
+	}
+	if len(generated) == 0 {
+		return
+	}
+
+	omitted := make(map[string]bool)
+	defs := g.output.Defs[:0]
+	for _, def := range g.output.Defs {
+		if generated[def.File] {
+			if g.opt.OmitGenerated {
+				omitted[def.DefKey.String()] = true
+				continue
+			}
+			def.Generated = true
+		}
+		defs = append(defs, def)
+	}
+	g.output.Defs = defs
+
+	refs := g.output.Refs[:0]
+	for _, ref := range g.output.Refs {
+		if generated[ref.File] {
+			if g.opt.OmitGenerated {
+				continue
+			}
+			ref.Generated = true
+		}
+		refs = append(refs, ref)
+	}
+	g.output.Refs = refs
+
+	docs := g.output.Docs[:0]
+	for _, doc := range g.output.Docs {
+		if generated[doc.File] {
+			if g.opt.OmitGenerated {
+				continue
+			}
+			doc.Generated = true
+		}
+		docs = append(docs, doc)
+	}
+	g.output.Docs = docs
+
+	if !g.opt.OmitGenerated {
+		return
+	}
+	rels := g.output.Relations[:0]
+	for _, rel := range g.output.Relations {
+		if !omitted[rel.From.String()] {
+			rels = append(rels, rel)
+		}
+	}
+	g.output.Relations = rels
+
+	anns := g.output.Annotations[:0]
+	for _, a := range g.output.Annotations {
+		if !generated[a.File] {
+			anns = append(anns, a)
+		}
+	}
+	g.output.Annotations = anns
+}
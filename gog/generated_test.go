@@ -0,0 +1,124 @@
+package gog
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGenerated(t *testing.T) {
+	srcs := []string{`// Code generated by stringer -type=Color; DO NOT EDIT.
+
+package foo
+
+// String returns the color's name.
+func (c Color) String() string { return "red" }
+`, `package foo
+
+// Color is a color.
+type Color int
+
+// Name is not generated.
+func Name(c Color) string { return c.String() }
+
+// Code generated by hand; DO NOT EDIT.
+var x = 1
+`}
+	filenames := []string{"color_string.go", "color.go"}
+	prog := createPkg(t, "foo", srcs, filenames)
+	pkgInfo := prog.Created[0]
+
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
+	var generatedDefs, generatedDocs []string
+	generatedRefs := map[string]bool{}
+	for _, def := range output.Defs {
+		if def.Generated != (def.File == "color_string.go") {
+			t.Errorf("def %s in %s: got Generated %v", def.Name, def.File, def.Generated)
+		}
+		if def.Generated {
+			generatedDefs = append(generatedDefs, def.Name)
+		}
+	}
+	for _, ref := range output.Refs {
+		if ref.Generated != (ref.File == "color_string.go") {
+			t.Errorf("ref to %v in %s: got Generated %v", ref.Def.Path, ref.File, ref.Generated)
+		}
+		generatedRefs[ref.File] = generatedRefs[ref.File] || ref.Generated
+	}
+	for _, doc := range output.Docs {
+		if doc.Generated {
+			generatedDocs = append(generatedDocs, doc.Data)
+		}
+	}
+	sort.Strings(generatedDefs)
+	if want := []string{"String", "c"}; !reflect.DeepEqual(generatedDefs, want) {
+		t.Errorf("got generated defs %q, want %q", generatedDefs, want)
+	}
+	if !generatedRefs["color_string.go"] || generatedRefs["color.go"] {
+		t.Errorf("got generated refs by file %v", generatedRefs)
+	}
+	if len(generatedDocs) == 0 {
+		t.Error("got no generated docs")
+	}
+
+	output = Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true, OmitGenerated: true})
+	for _, def := range output.Defs {
+		if def.File == "color_string.go" {
+			t.Errorf("got def %s in generated file with OmitGenerated", def.Name)
+		}
+	}
+	for _, doc := range output.Docs {
+		if doc.File == "color_string.go" {
+			t.Errorf("got doc %q in generated file with OmitGenerated", doc.Data)
+		}
+	}
+	var refToString bool
+	for _, ref := range output.Refs {
+		if ref.File == "color_string.go" {
+			t.Errorf("got ref to %v in generated file with OmitGenerated", ref.Def.Path)
+		}
+		refToString = refToString || reflect.DeepEqual(ref.Def.Path, []string{"Color", "String"})
+	}
+	if !refToString {
+		t.Error("got no ref to the generated Color.String method with OmitGenerated")
+	}
+}
+
+func TestOmitGenerated(t *testing.T) {
+	src := `// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: foo.proto
+
+//go:generate protoc --go_out=. foo.proto
+
+package foo
+
+type Request struct{}
+
+//line parser.y:10
+func Parse() {}
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.pb.go"})
+	pkgInfo := prog.Created[0]
+	pf := ScanProtoFile("foo.proto", "foo.proto", []byte("syntax = \"proto3\";\nmessage Request {}\n"))
+
+	// defs after a //line directive are in generated files too
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+	for _, def := range output.Defs {
+		if def.Name == "Parse" && (def.File != "parser.y" || !def.Generated) {
+			t.Errorf("got def Parse in %s with Generated %v, want generated in parser.y", def.File, def.Generated)
+		}
+	}
+
+	output = Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{ProtoFiles: []*ProtoFile{pf}, OmitGenerated: true})
+	for _, def := range output.Defs {
+		if def.Kind != "proto" && def.Kind != "package" {
+			t.Errorf("got def %s in %s with OmitGenerated", def.Name, def.File)
+		}
+	}
+	for _, rel := range output.Relations {
+		t.Errorf("got relation %s from %v, which is omitted", rel.Kind, rel.From.Path)
+	}
+	for _, a := range output.Annotations {
+		t.Errorf("got annotation %s in generated file %s with OmitGenerated", a.Type, a.File)
+	}
+}
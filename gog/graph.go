@@ -11,23 +11,120 @@ import (
 	"go/types"
 
 	_ "golang.org/x/tools/go/gcimporter15"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
 )
 
 type Output struct {
-	Defs []*Def
-	Refs []*Ref
-	Docs []*Doc
+	Defs        []*Def
+	Refs        []*Ref
+	Docs        []*Doc
+	Relations   []*Relation   `json:",omitempty"`
+	Annotations []*Annotation `json:",omitempty"`
 }
 
 func (o *Output) Append(o2 *Output) {
 	o.Defs = append(o.Defs, o2.Defs...)
 	o.Refs = append(o.Refs, o2.Refs...)
 	o.Docs = append(o.Docs, o2.Docs...)
+	o.Relations = append(o.Relations, o2.Relations...)
+	o.Annotations = append(o.Annotations, o2.Annotations...)
+}
+
+// Options configures what Graph emits in addition to defs and refs.
+type Options struct {
+	// IncludeDocs is whether to emit docs for defs and unattached
+	// comments.
+	IncludeDocs bool
+
+	// MarkdownDocs is whether to also emit a "text/markdown" rendering
+	// of each doc (in addition to "text/html" and "text/plain").
+	MarkdownDocs bool
+
+	// OmitComments is the set of classes of unattached comments
+	// (CommentLicense, CommentNote, etc.) not to emit docs for.
+	OmitComments map[string]bool
+
+	// DepDeprecations holds the deprecations declared in dependencies
+	// (keyed by import path), so that refs to deprecated defs in
+	// other packages can be flagged.
+	DepDeprecations map[string]Deprecations
+
+	// PositionEncoding, if set, is the encoding (one of
+	// PositionEncodings) of the line/character ranges to emit in
+	// addition to the byte offset spans of defs, refs and docs.
+	PositionEncoding string
+
+	// Sources are the contents of the files being graphed (keyed by
+	// the names they were parsed with), for computing the ranges of
+	// files that aren't on disk in the utf-16 and utf-32 position
+	// encodings. Files not in Sources are read from disk.
+	Sources map[string][]byte
+
+	// Excluded is whether the files being graphed are excluded from
+	// the package's build (e.g., by build constraints) and were
+	// type-checked on a best-effort basis. Only the defs, refs and
+	// docs within them are emitted (not the package def, package doc
+	// or relations, which are emitted when graphing the package's
+	// other files), identifiers that the type checker could not
+	// resolve are resolved by syntax and scope, and all defs and refs
+	// are marked as low confidence. The paths of the defs in excluded
+	// files are prefixed with their filenames, since several excluded
+	// files (e.g., for different platforms) and the package's other
+	// files can declare the same names.
+	Excluded bool
+
+	// PackageFiles are the package's other files, which the excluded
+	// files being graphed were type-checked along with (see Excluded).
+	// Nothing in them is emitted, but their scopes are needed to
+	// assign paths.
+	PackageFiles []*ast.File
+
+	// CSymbols are the symbols declared in the package's C source and
+	// header files (see ScanCFile), which C.name refs in cgo files
+	// can refer to in addition to those declared in cgo preambles.
+	CSymbols []*CSymbol
+
+	// AsmSymbols are the symbols in the package's assembly files (see
+	// ScanAsmFile), which are emitted as refs to the Go defs they
+	// implement or as defs of their own.
+	AsmSymbols []*AsmSymbol
+
+	// OmitGenerated is whether to omit the defs, refs and docs in
+	// generated files (see IsGenerated) instead of flagging them, along
+	// with the annotations in those files and the relations from their
+	// defs. Refs from other files (and other units) to the defs in
+	// generated files are still emitted.
+	OmitGenerated bool
+
+	// ProtoFiles are the .proto files that the package's generated Go
+	// files were generated from (see ProtoSource and ScanProtoFile),
+	// whose declarations are emitted as defs that the generated Go defs
+	// are related to.
+	ProtoFiles []*ProtoFile
+
+	// Capabilities is whether to record the sensitive capabilities
+	// (using unsafe, reflect, syscalls, os/exec or cgo) that each
+	// package-level def's refs need, and their union on the package
+	// def.
+	Capabilities bool
+
+	// Metrics is whether to compute size and complexity metrics of
+	// funcs, methods and types (see definfo.Metrics).
+	Metrics bool
+
+	// Files are the files in the package's directory and its
+	// subdirectories (relative to it, with slash separators), which
+	// //go:embed patterns are matched against.
+	Files []string
 }
 
 type grapher struct {
+	opt Options
+
 	fset      *token.FileSet
 	files     []*ast.File
+	checked   []*ast.File // files and Options.PackageFiles
 	typesPkg  *types.Package
 	typesInfo *types.Info
 
@@ -49,17 +146,39 @@ type grapher struct {
 
 	seenDocObjs map[types.Object]struct{}
 	seenDocKeys map[string]struct{}
+
+	deprecated    map[types.Object]string
+	pkgDeprecated string
+
+	enums map[*types.TypeName][]definfo.EnumValue
+
+	genDecls    map[ast.Spec]*ast.GenDecl
+	promotedVia map[*ast.Ident][]*types.Var
+	selNames    map[*ast.Ident]bool
+
+	cSymbols map[string]*CSymbol
+	embeds   map[types.Object][]string
+
+	initSteps []initStep
+	initOrder map[types.Object]int
+
+	entryPoints map[types.Object]entryPoint
+
+	importNames map[*types.PkgName]*DefKey
 }
 
-func Graph(fset *token.FileSet, files []*ast.File, typesPkg *types.Package, typesInfo *types.Info, includeDocs bool) *Output {
+func Graph(fset *token.FileSet, files []*ast.File, typesPkg *types.Package, typesInfo *types.Info, opt Options) *Output {
 	if len(files) == 0 {
 		log.Printf("warning: attempted to graph package %s with no files", typesPkg.Path())
 		return &Output{}
 	}
 
 	g := &grapher{
+		opt: opt,
+
 		fset:      fset,
 		files:     files,
+		checked:   append(append([]*ast.File{}, files...), opt.PackageFiles...),
 		typesPkg:  typesPkg,
 		typesInfo: typesInfo,
 
@@ -75,20 +194,65 @@ func Graph(fset *token.FileSet, files []*ast.File, typesPkg *types.Package, type
 		pkgscope:   make(map[types.Object]bool),
 		selRecvs:   make(map[types.Object]types.Type),
 
+		genDecls:    make(map[ast.Spec]*ast.GenDecl),
+		promotedVia: make(map[*ast.Ident][]*types.Var),
+		selNames:    make(map[*ast.Ident]bool),
+
 		output: &Output{},
 	}
 
 	g.buildScopeInfo(typesInfo)
 	g.assignPathsInPackage(typesPkg)
-
-	g.output.Defs = append(g.output.Defs, g.NewPackageDef(filepath.Dir(g.fset.Position(files[0].Package).Filename), typesPkg))
+	g.buildDeprecations()
+	g.buildEnums()
+	g.buildCSymbols()
+	g.buildEmbeds()
+	g.buildInitOrder()
+	g.buildEntryPoints()
+	g.buildImportNames()
+
+	if !opt.Excluded {
+		g.output.Defs = append(g.output.Defs, g.NewPackageDef(filepath.Dir(g.fset.Position(files[0].Package).Filename), typesPkg))
+		g.emitCSymbols()
+		g.emitAsmSymbols()
+	}
 
 	for _, f := range files {
 		ast.Walk(g, f)
 	}
+	g.emitDirectives(files)
+
+	if !opt.Excluded {
+		g.output.Relations = append(g.output.Relations, g.emitTestRelations(files)...)
+		g.output.Relations = append(g.output.Relations, g.emitPromotions()...)
+		g.output.Relations = append(g.output.Relations, g.emitOverrides()...)
+		g.output.Relations = append(g.output.Relations, g.emitInterfaceEmbeds()...)
+		g.output.Relations = append(g.output.Relations, g.emitProtoSymbols(files)...)
+		g.output.Relations = append(g.output.Relations, g.emitInitUses()...)
+	}
 
-	if includeDocs {
+	if opt.IncludeDocs {
 		g.output.Docs = g.emitDocs(files, typesPkg, typesInfo)
+		g.output.Docs = append(g.output.Docs, g.emitExamples(files)...)
+	}
+
+	if opt.Excluded {
+		for _, def := range g.output.Defs {
+			def.LowConfidence = true
+		}
+		for _, ref := range g.output.Refs {
+			ref.LowConfidence = true
+		}
+	}
+
+	if opt.Capabilities {
+		g.addCapabilities(files)
+	}
+
+	g.markGenerated(files)
+
+	if opt.PositionEncoding != "" {
+		g.addRanges()
 	}
 
 	return g.output
@@ -103,9 +267,12 @@ func (g *grapher) Visit(node ast.Node) (w ast.Visitor) {
 		g.output.Refs = append(g.output.Refs, ref)
 
 	case *ast.ImportSpec:
-		if obj := g.typesInfo.Implicits[n]; obj != nil {
-			ref := g.NewRef(n, obj, g.typesPkg.Path())
-			g.output.Refs = append(g.output.Refs, ref)
+		g.visitImportSpec(n)
+		return nil
+
+	case *ast.GenDecl:
+		for _, spec := range n.Specs {
+			g.genDecls[spec] = n
 		}
 
 	case *ast.TypeSpec:
@@ -145,14 +312,17 @@ func (g *grapher) Visit(node ast.Node) (w ast.Visitor) {
 		g.newDef(n, n.Value)
 
 	case *ast.SelectorExpr:
+		g.selNames[n.Sel] = true
+		if g.isCPkg(n.X) {
+			if ref := g.cRef(n.Sel); ref != nil {
+				g.output.Refs = append(g.output.Refs, ref)
+			}
+		}
 		if sel := g.typesInfo.Selections[n]; sel != nil {
 			recv := sel.Recv()
-			index := sel.Index()
-			for i := 0; i < len(index)-1; i++ {
-				if ptr, ok := recv.Underlying().(*types.Pointer); ok {
-					recv = ptr.Elem()
-				}
-				recv = recv.Underlying().(*types.Struct).Field(index[i]).Type()
+			if chain := g.embeddingChain(sel); chain != nil {
+				recv = chain[len(chain)-1].Type()
+				g.promotedVia[n.Sel] = chain
 			}
 			g.selRecvs[sel.Obj()] = recv
 		}
@@ -161,10 +331,27 @@ func (g *grapher) Visit(node ast.Node) (w ast.Visitor) {
 		if n.Name == "_" {
 			break
 		}
-		if obj := g.typesInfo.ObjectOf(n); obj != nil {
+		obj := g.typesInfo.ObjectOf(n)
+		if obj == nil && g.opt.Excluded {
+			obj = g.resolveBySyntax(n)
+		}
+		if obj != nil {
 			ref := g.NewRef(n, obj, g.typesPkg.Path())
 			ref.IsDef = (g.typesInfo.Defs[n] != nil)
+			if ref.IsDef {
+				// only uses of a deprecated def are flagged
+				ref.Deprecated = false
+			}
 			g.output.Refs = append(g.output.Refs, ref)
+
+			// Promoted fields and methods implicitly refer to the
+			// embedded fields they are reached through.
+			for _, f := range g.promotedVia[n] {
+				fref := g.NewRef(n, f, g.typesPkg.Path())
+				fref.Implicit = true
+				ref.Via = append(ref.Via, fref.Def)
+				g.output.Refs = append(g.output.Refs, fref)
+			}
 		}
 
 	case *ast.LabeledStmt:
@@ -231,6 +418,9 @@ func (g *grapher) makeDefInfo(obj types.Object) (*DefKey, *defInfo) {
 			return &DefKey{"builtin", []string{obj.Name()}}, &defInfo{pkgscope: false, exported: true}
 		}
 	case *types.PkgName:
+		if key, ok := g.importNames[obj]; ok {
+			return key, &defInfo{pkgscope: false, exported: false}
+		}
 		return &DefKey{obj.Imported().Path(), []string{}}, &defInfo{pkgscope: false, exported: true}
 	case *types.Const:
 		var pkg string
@@ -254,7 +444,9 @@ func (g *grapher) makeDefInfo(obj types.Object) (*DefKey, *defInfo) {
 	// Handle the case where a dir has 2 main packages that are not
 	// intended to be compiled together and have overlapping def
 	// paths. Prefix the def path with the filename.
-	if obj.Pkg().Name() == "main" {
+	// The same goes for excluded files, which are not compiled with
+	// each other either.
+	if obj.Pkg().Name() == "main" || (g.opt.Excluded && g.inFiles(obj.Pos())) {
 		p := g.fset.Position(obj.Pos())
 		path = append([]string{filepath.Base(p.Filename)}, path...)
 	}
@@ -0,0 +1,110 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+	"path/filepath"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// buildImportNames records the def keys of the names given to imported
+// packages (f in `import f "fmt"`), so that uses of the names refer to
+// their defs (see newImportNameDef).
+func (g *grapher) buildImportNames() {
+	g.importNames = make(map[*types.PkgName]*DefKey)
+	for _, f := range g.files {
+		for _, spec := range f.Imports {
+			if spec.Name == nil || spec.Name.Name == "_" || spec.Name.Name == "." {
+				continue
+			}
+			if pn, ok := g.typesInfo.Defs[spec.Name].(*types.PkgName); ok {
+				g.importNames[pn] = g.importNameKey(spec, pn)
+			}
+		}
+	}
+}
+
+// visitImportSpec emits a ref from the import path literal of spec to
+// the imported package and, if spec names the package, a def for the
+// name.
+//
+// Uses of a named import's name (e.g., f in f.Println) refer to the
+// name's def (which carries the import's doc), and those of an
+// unnamed import's name to the imported package. The identifiers that
+// a dot import brings into scope are resolved to their defs in the
+// imported package like qualified identifiers are.
+func (g *grapher) visitImportSpec(spec *ast.ImportSpec) {
+	var pn *types.PkgName
+	if spec.Name != nil {
+		pn, _ = g.typesInfo.Defs[spec.Name].(*types.PkgName)
+	}
+	if pn == nil {
+		pn, _ = g.typesInfo.Implicits[spec].(*types.PkgName)
+	}
+	if pn == nil {
+		// the import failed
+		return
+	}
+
+	// pn refers to the name's def if spec names the package
+	pkgObj := types.NewPkgName(spec.Path.Pos(), g.typesPkg, pn.Imported().Name(), pn.Imported())
+	g.output.Refs = append(g.output.Refs, g.NewRef(spec.Path, pkgObj, g.typesPkg.Path()))
+
+	if spec.Name == nil {
+		return
+	}
+	switch spec.Name.Name {
+	case "_":
+	case ".":
+		g.output.Refs = append(g.output.Refs, g.NewRef(spec.Name, pn, g.typesPkg.Path()))
+	default:
+		def := g.newImportNameDef(spec, pn)
+		g.output.Defs = append(g.output.Defs, def)
+		g.output.Refs = append(g.output.Refs, &Ref{
+			Unit:  g.typesPkg.Path(),
+			File:  def.File,
+			Span:  def.IdentSpan,
+			Def:   def.DefKey,
+			IsDef: true,
+		})
+	}
+}
+
+// importNameKey returns the def key of the name given to the package
+// imported by spec. Because import names are file-scoped, its path is
+// uniquified like those of local defs.
+func (g *grapher) importNameKey(spec *ast.ImportSpec, pn *types.PkgName) *DefKey {
+	pos := g.fset.Position(spec.Name.Pos())
+	path := []string{pn.Name() + uniqID(pos)}
+	if g.typesPkg.Name() == "main" {
+		path = append([]string{filepath.Base(pos.Filename)}, path...)
+	}
+	return &DefKey{PackageImportPath: g.typesPkg.Path(), Path: path}
+}
+
+// newImportNameDef creates a new Def for the name given to the package
+// imported by spec (f in `import f "fmt"`).
+func (g *grapher) newImportNameDef(spec *ast.ImportSpec, pn *types.PkgName) *Def {
+	pos := g.fset.Position(spec.Name.Pos())
+	header, body, full := g.declSpans(spec)
+	return &Def{
+		Name: pn.Name(),
+
+		DefKey: g.importNames[pn],
+
+		File:      pos.Filename,
+		IdentSpan: makeSpan(g.fset, spec.Name),
+		DeclSpan:  makeSpan(g.fset, spec),
+
+		HeaderSpan: header,
+		BodySpan:   body,
+		FullSpan:   full,
+
+		DefInfo: definfo.DefInfo{
+			PkgName:         g.typesPkg.Name(),
+			Kind:            definfo.ImportName,
+			ImportedPackage: pn.Imported().Path(),
+		},
+	}
+}
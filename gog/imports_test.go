@@ -0,0 +1,85 @@
+package gog
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestImportRefs(t *testing.T) {
+	src := `package foo
+
+import (
+	"errors"
+	// f formats.
+	f "fmt"
+	. "strings"
+	_ "unsafe"
+)
+
+var _ = errors.New(f.Sprint(ToUpper("x")))
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.go"})
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
+
+	var refs []string
+	for _, ref := range output.Refs {
+		if ref.Def.PackageImportPath == "foo" && len(ref.Def.Path) == 0 {
+			continue
+		}
+		s := src[ref.Span[0]:ref.Span[1]] + " -> " + ref.Def.PackageImportPath + "#" + strings.Join(ref.Def.Path, "/")
+		if ref.IsDef {
+			s += " (def)"
+		}
+		refs = append(refs, s)
+	}
+	sort.Strings(refs)
+	want := []string{
+		`"errors" -> errors#`,
+		`"fmt" -> fmt#`,
+		`"strings" -> strings#`,
+		`"unsafe" -> unsafe#`,
+		`. -> strings#`,
+		`New -> errors#New`,
+		`Sprint -> fmt#Sprint`,
+		`ToUpper -> strings#ToUpper`,
+		`errors -> errors#`,
+		`f -> foo#f$foo48`,
+		`f -> foo#f$foo48 (def)`,
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs\n%s\nwant\n%s", strings.Join(refs, "\n"), strings.Join(want, "\n"))
+	}
+
+	var found bool
+	for _, def := range output.Defs {
+		if def.Kind != definfo.ImportName {
+			continue
+		}
+		found = true
+		if def.Name != "f" || def.ImportedPackage != "fmt" || src[def.DeclSpan[0]:def.DeclSpan[1]] != `f "fmt"` {
+			t.Errorf("got import name def %+v", def)
+		}
+	}
+	if !found {
+		t.Error("no import name def")
+	}
+
+	var docFound bool
+	for _, doc := range output.Docs {
+		if doc.Data != "f formats.\n" {
+			continue
+		}
+		docFound = true
+		if doc.DefKey == nil || !reflect.DeepEqual(doc.DefKey.Path, []string{"f$foo48"}) {
+			t.Errorf("got import doc for %v, want for the import name def", doc.DefKey)
+		}
+	}
+	if !docFound {
+		t.Error("no import doc")
+	}
+}
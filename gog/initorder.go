@@ -0,0 +1,120 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// initStep is a step in the package's initialization: the
+// initialization of package-level vars by an initializer expression, or
+// the execution of an init func.
+type initStep struct {
+	objs []types.Object // the vars initialized, or the init func
+	node ast.Node       // the initializer expression or func body
+}
+
+// buildInitOrder records the package's initialization order: its
+// package-level var initializers in the order the type checker
+// computed (types.Info.InitOrder), followed by its init funcs in the
+// order they appear in the files. Files excluded from the build are
+// never initialized, so nothing is recorded for them; neither is it for
+// test files, which are only initialized in the test binary.
+func (g *grapher) buildInitOrder() {
+	g.initOrder = make(map[types.Object]int)
+	if g.opt.Excluded {
+		return
+	}
+	isTest := func(n ast.Node) bool {
+		return strings.HasSuffix(g.fset.Position(n.Pos()).Filename, "_test.go")
+	}
+	for _, init := range g.typesInfo.InitOrder {
+		if isTest(init.Rhs) {
+			continue
+		}
+		step := initStep{node: init.Rhs}
+		for _, v := range init.Lhs {
+			step.objs = append(step.objs, v)
+		}
+		g.addInitStep(step)
+	}
+	for _, f := range g.files {
+		if isTest(f) {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil || fd.Name.Name != "init" || fd.Body == nil {
+				continue
+			}
+			if obj := g.typesInfo.Defs[fd.Name]; obj != nil {
+				g.addInitStep(initStep{objs: []types.Object{obj}, node: fd.Body})
+			}
+		}
+	}
+}
+
+func (g *grapher) addInitStep(step initStep) {
+	g.initSteps = append(g.initSteps, step)
+	for _, obj := range step.objs {
+		g.initOrder[obj] = len(g.initSteps)
+	}
+}
+
+// emitInitUses emits relations from the vars and init funcs in the
+// package's initialization order to the package-level vars and the
+// funcs and methods (in any package) that their initializers or bodies
+// refer to, which are used at import time. Blank vars have no def, so
+// the uses of their initializers are related to the package def.
+func (g *grapher) emitInitUses() []*Relation {
+	pkgKey := &DefKey{PackageImportPath: g.typesPkg.Path(), Path: []string{}}
+	pkgUses := make(map[types.Object]bool)
+	var rels []*Relation
+	for _, step := range g.initSteps {
+		var uses []types.Object
+		seen := make(map[types.Object]bool)
+		ast.Inspect(step.node, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := g.typesInfo.Uses[ident]
+			if obj == nil || obj.Pkg() == nil || seen[obj] {
+				return true
+			}
+			switch obj := obj.(type) {
+			case *types.Var:
+				if obj.Parent() != obj.Pkg().Scope() {
+					return true
+				}
+			case *types.Func:
+			default:
+				return true
+			}
+			seen[obj] = true
+			uses = append(uses, obj)
+			return true
+		})
+
+		for _, obj := range step.objs {
+			from := pkgKey
+			if obj.Name() != "_" {
+				from, _ = g.defInfo(obj)
+			}
+			for _, use := range uses {
+				if use == obj {
+					continue
+				}
+				if from == pkgKey {
+					if pkgUses[use] {
+						continue
+					}
+					pkgUses[use] = true
+				}
+				to, _ := g.defInfo(use)
+				rels = append(rels, &Relation{Kind: RelationInitUses, From: from, To: to})
+			}
+		}
+	}
+	return rels
+}
@@ -0,0 +1,81 @@
+package gog
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestInitOrder(t *testing.T) {
+	srcs := []string{`package foo
+
+import "strings"
+
+var a = b + c()
+
+var b, d = pair()
+
+func c() int { return len(strings.Repeat("x", 3)) }
+
+func pair() (int, int) { return 1, 2 }
+
+func init() { a++ }
+`, `package foo
+
+var e = 1
+
+var _, _ = register(e), register(b)
+
+func register(int) bool { return true }
+
+func init() { d = e }
+`, `package foo
+
+// test files are only initialized in the test binary
+var f = e + c()
+
+func init() { _ = register(f) }
+`}
+	prog := createPkg(t, "foo", srcs, []string{"a.go", "b.go", "foo_test.go"})
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	var order []string
+	for _, def := range output.Defs {
+		if def.InitOrder != 0 {
+			order = append(order, fmt.Sprintf("%d %s@%s", def.InitOrder, def.Name, def.File))
+		}
+	}
+	sort.Strings(order)
+	wantOrder := []string{"1 b@a.go", "1 d@a.go", "2 a@a.go", "3 e@b.go", "6 init@a.go", "7 init@b.go"}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("got init order %q, want %q", order, wantOrder)
+	}
+
+	var uses []string
+	for _, rel := range output.Relations {
+		if rel.Kind == RelationInitUses {
+			uses = append(uses, strings.Join(rel.From.Path, "/")+" -> "+rel.To.PackageImportPath+"#"+strings.Join(rel.To.Path, "/"))
+		}
+	}
+	sort.Strings(uses)
+	wantUses := []string{
+		// blank vars' uses are related to the package def
+		" -> foo#b",
+		" -> foo#e",
+		" -> foo#register",
+
+		"a -> foo#b",
+		"a -> foo#c",
+		"b -> foo#pair",
+		"d -> foo#pair",
+		"init$a165 -> foo#a",
+		"init$b107 -> foo#d",
+		"init$b107 -> foo#e",
+	}
+	if !reflect.DeepEqual(uses, wantUses) {
+		t.Errorf("got init uses\n%s\nwant\n%s", strings.Join(uses, "\n"), strings.Join(wantUses, "\n"))
+	}
+}
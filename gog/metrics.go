@@ -0,0 +1,126 @@
+package gog
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// metrics returns the size and complexity metrics of obj, which is
+// declared by declNode, or nil if there are none for its kind of def.
+func (g *grapher) metrics(obj types.Object, declNode ast.Node) *definfo.Metrics {
+	switch obj := obj.(type) {
+	case *types.Func:
+		fd, ok := declNode.(*ast.FuncDecl)
+		if !ok {
+			return nil
+		}
+		m := &definfo.Metrics{
+			Lines:  g.fset.Position(fd.End()).Line - g.fset.Position(fd.Pos()).Line + 1,
+			Params: obj.Type().(*types.Signature).Params().Len(),
+		}
+		if fd.Body != nil {
+			funcBodyMetrics(fd.Body, m)
+		}
+		return m
+
+	case *types.TypeName:
+		if obj.IsAlias() {
+			return nil
+		}
+		m := &definfo.Metrics{}
+		switch u := obj.Type().Underlying().(type) {
+		case *types.Struct:
+			m.Fields = u.NumFields()
+		case *types.Interface:
+			m.Methods = u.NumMethods()
+		}
+		if named, ok := obj.Type().(*types.Named); ok && !types.IsInterface(named) {
+			m.Methods = named.NumMethods()
+		}
+		return m
+	}
+	return nil
+}
+
+// funcBodyMetrics sets the statement count, cyclomatic complexity,
+// nesting depth and return count in m for the func body.
+func funcBodyMetrics(body *ast.BlockStmt, m *definfo.Metrics) {
+	m.Complexity = 1
+
+	var stack []ast.Node
+	depth, funcLits := 0, 0
+	elseIfs := make(map[ast.Node]bool)
+	nests := func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.IfStmt:
+			// "else if" doesn't nest any deeper than its "if"
+			return !elseIfs[n]
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			return true
+		}
+		return false
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if nests(top) {
+				depth--
+			}
+			if _, ok := top.(*ast.FuncLit); ok {
+				funcLits--
+			}
+			return true
+		}
+		stack = append(stack, n)
+		if nests(n) {
+			depth++
+			if depth > m.Nesting {
+				m.Nesting = depth
+			}
+		}
+
+		if _, ok := n.(ast.Stmt); ok {
+			switch n.(type) {
+			case *ast.BlockStmt, *ast.EmptyStmt, *ast.CaseClause, *ast.CommClause:
+			default:
+				m.Statements++
+			}
+		}
+
+		switch n := n.(type) {
+		case *ast.IfStmt:
+			m.Complexity++
+			if n.Else != nil {
+				if _, ok := n.Else.(*ast.IfStmt); ok {
+					elseIfs[n.Else] = true
+				}
+			}
+		case *ast.ForStmt, *ast.RangeStmt:
+			m.Complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				m.Complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				m.Complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				m.Complexity++
+			}
+		case *ast.FuncLit:
+			funcLits++
+		case *ast.ReturnStmt:
+			if funcLits == 0 {
+				m.Returns++
+			}
+		}
+		return true
+	})
+}
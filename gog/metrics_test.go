@@ -0,0 +1,68 @@
+package gog
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestMetrics(t *testing.T) {
+	src := `package foo
+
+type T struct {
+	A, B int
+	c    string
+}
+
+func (T) M1()  {}
+func (*T) M2() {}
+
+type I interface {
+	X()
+	Y()
+}
+
+func F(a, b int, rest ...string) (int, error) {
+	if a > 0 && b > 0 {
+		for _, s := range rest {
+			switch s {
+			case "x", "y":
+				return 1, nil
+			default:
+			}
+		}
+	} else if a < 0 {
+		f := func() int { return 2 }
+		return f(), nil
+	}
+	return 0, nil
+}
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.go"})
+	pkgInfo := prog.Created[0]
+
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{Metrics: true})
+	want := map[string]*definfo.Metrics{
+		"T":  {Fields: 3, Methods: 2},
+		"M1": {Lines: 1, Complexity: 1},
+		"I":  {Methods: 2},
+		"F":  {Lines: 15, Statements: 9, Complexity: 6, Nesting: 3, Params: 3, Returns: 3},
+	}
+	for _, def := range output.Defs {
+		if w, ok := want[def.Name]; ok {
+			if !reflect.DeepEqual(def.Metrics, w) {
+				t.Errorf("%s: got metrics %+v, want %+v", def.Name, def.Metrics, w)
+			}
+		} else if def.Metrics != nil && def.Name != "M2" && def.Name != "X" && def.Name != "Y" {
+			t.Errorf("%s: got metrics %+v, want none", def.Name, def.Metrics)
+		}
+	}
+
+	output = Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+	for _, def := range output.Defs {
+		if def.Metrics != nil {
+			t.Errorf("%s: got metrics %+v without Options.Metrics", def.Name, def.Metrics)
+		}
+	}
+}
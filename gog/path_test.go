@@ -44,7 +44,7 @@ func TestPaths(t *testing.T) {
 		prog := createPkg(t, "foo", []string{src}, nil)
 
 		pkgInfo := prog.Created[0]
-		output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, false)
+		output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
 
 		var paths []defPath
 		for _, s := range output.Defs {
@@ -84,3 +84,43 @@ func TestPaths(t *testing.T) {
 		}
 	}
 }
+
+func TestEmbeddedInterfaceMethodPaths(t *testing.T) {
+	src := `package foo
+
+import "fmt"
+
+type A interface {
+	fmt.Stringer
+	Z()
+}
+
+func f(s fmt.Stringer) string { return s.String() }
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"foo.go"})
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	// Only A's explicit methods are assigned paths under A. Its method
+	// set also has the methods of the interfaces it embeds (which sort
+	// before Z here), whose defs are elsewhere.
+	want := map[string]string{"Z": "foo#A/Z (def)", "String": "fmt#Stringer/String"}
+	for _, ref := range output.Refs {
+		name := src[ref.Span[0]:ref.Span[1]]
+		w, ok := want[name]
+		if !ok {
+			continue
+		}
+		got := ref.Def.PackageImportPath + "#" + strings.Join(ref.Def.Path, "/")
+		if ref.IsDef {
+			got += " (def)"
+		}
+		if got != w {
+			t.Errorf("got ref %s -> %s, want %s", name, got, w)
+		}
+		delete(want, name)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing refs to %v", want)
+	}
+}
@@ -0,0 +1,155 @@
+package gog
+
+import (
+	"go/token"
+	"io/ioutil"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// Position encodings, which determine how the characters of a line
+// are counted in positions: in UTF-8 bytes, UTF-16 code units (as in
+// the Language Server Protocol) or UTF-32 code units (i.e., runes).
+const (
+	PositionEncodingUTF8  = "utf-8"
+	PositionEncodingUTF16 = "utf-16"
+	PositionEncodingUTF32 = "utf-32"
+)
+
+// PositionEncodings are the supported position encodings.
+var PositionEncodings = []string{PositionEncodingUTF8, PositionEncodingUTF16, PositionEncodingUTF32}
+
+// positioner converts byte offset spans to line/character ranges.
+type positioner struct {
+	encoding string
+	sources  map[string][]byte
+
+	// files are the token files by the filenames that the defs, refs
+	// and docs in them can have: their own names, and the names that
+	// their //line directives give.
+	files    map[string][]*token.File
+	contents map[*token.File][]byte
+}
+
+func (g *grapher) newPositioner() *positioner {
+	p := &positioner{
+		encoding: g.opt.PositionEncoding,
+		sources:  g.opt.Sources,
+		files:    make(map[string][]*token.File),
+		contents: make(map[*token.File][]byte),
+	}
+	for _, f := range g.files {
+		tf := g.fset.File(f.Pos())
+		if tf == nil {
+			continue
+		}
+		for name := range fileNames(tf) {
+			p.files[name] = append(p.files[name], tf)
+		}
+	}
+	return p
+}
+
+// fileNames returns the filenames that the defs, refs and docs in tf
+// can have: its own name, and the names that its //line directives
+// give.
+func fileNames(tf *token.File) map[string]bool {
+	names := map[string]bool{tf.Name(): true}
+	for _, off := range tf.Lines() {
+		names[tf.PositionFor(tf.Pos(off), true).Filename] = true
+	}
+	return names
+}
+
+// addRanges sets the line/character ranges of the defs, refs and docs
+// in g.output from their spans, according to Options.PositionEncoding.
+func (g *grapher) addRanges() {
+	p := g.newPositioner()
+	for _, def := range g.output.Defs {
+		if def.Kind == definfo.Package {
+			continue
+		}
+		def.IdentRange = p.rangeOf(def.File, def.IdentSpan)
+		def.DeclRange = p.rangeOf(def.File, def.DeclSpan)
+		def.HeaderRange = p.rangeOf(def.File, def.HeaderSpan)
+		if def.BodySpan != [2]uint32{} {
+			def.BodyRange = p.rangeOf(def.File, def.BodySpan)
+		}
+		def.FullRange = p.rangeOf(def.File, def.FullSpan)
+	}
+	for _, ref := range g.output.Refs {
+		ref.Range = p.rangeOf(ref.File, ref.Span)
+	}
+	for _, doc := range g.output.Docs {
+		if doc.Span != [2]uint32{} {
+			doc.Range = p.rangeOf(doc.File, doc.Span)
+		}
+	}
+}
+
+// rangeOf returns the range of span in filename, or nil if the range
+// can't be determined (e.g., because the file can't be read).
+func (p *positioner) rangeOf(filename string, span [2]uint32) *definfo.Range {
+	start, ok := p.position(filename, int(span[0]))
+	if !ok {
+		return nil
+	}
+	end, ok := p.position(filename, int(span[1]))
+	if !ok {
+		return nil
+	}
+	return &definfo.Range{Start: start, End: end}
+}
+
+// position returns the position at offset in the token file that
+// filename (which may have been given by a //line directive) refers
+// to. Like spans, positions are in the token file itself, not
+// adjusted by //line directives.
+func (p *positioner) position(filename string, offset int) (definfo.Position, bool) {
+	var tf *token.File
+	for _, f := range p.files[filename] {
+		if offset <= f.Size() && (len(p.files[filename]) == 1 || f.PositionFor(f.Pos(offset), true).Filename == filename) {
+			tf = f
+			break
+		}
+	}
+	if tf == nil {
+		return definfo.Position{}, false
+	}
+	pos := tf.PositionFor(tf.Pos(offset), false)
+	line, col := pos.Line-1, pos.Column-1
+	if p.encoding == PositionEncodingUTF8 {
+		return definfo.Position{Line: line, Character: col}, true
+	}
+
+	src := p.source(tf)
+	if src == nil {
+		return definfo.Position{}, false
+	}
+
+	var char int
+	for _, r := range string(src[offset-col : offset]) {
+		char++
+		if p.encoding == PositionEncodingUTF16 && r > 0xFFFF {
+			char++ // surrogate pair
+		}
+	}
+	return definfo.Position{Line: line, Character: char}, true
+}
+
+// source returns the contents of tf, from Options.Sources or else from
+// the file it was parsed from, or nil if they can't be read.
+func (p *positioner) source(tf *token.File) []byte {
+	src, ok := p.contents[tf]
+	if !ok {
+		src, ok = p.sources[tf.Name()]
+		if !ok {
+			src, _ = ioutil.ReadFile(tf.Name())
+		}
+		if len(src) != tf.Size() {
+			src = nil
+		}
+		p.contents[tf] = src
+	}
+	return src
+}
@@ -0,0 +1,92 @@
+package gog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestPositionEncodings(t *testing.T) {
+	src := `package foo
+
+var s = "héllo😀"; var X int
+`
+	dir, err := ioutil.TempDir("", "gog-position")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "foo.go")
+	if err := ioutil.WriteFile(filename, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+	prog := createPkgFromFiles(t, "foo", []string{filename})
+	pkgInfo := prog.Created[0]
+
+	// X is at byte 26 of its line, after a 2-byte character (é), a
+	// 4-byte character (😀, 2 UTF-16 code units) and 20 1-byte
+	// characters.
+	tests := map[string]int{
+		PositionEncodingUTF8:  26,
+		PositionEncodingUTF16: 23,
+		PositionEncodingUTF32: 22,
+	}
+	for enc, char := range tests {
+		output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{PositionEncoding: enc})
+		want := &definfo.Range{Start: definfo.Position{Line: 2, Character: char}, End: definfo.Position{Line: 2, Character: char + 1}}
+		var found bool
+		for _, def := range output.Defs {
+			if def.Name != "X" {
+				continue
+			}
+			found = true
+			if *def.IdentRange != *want {
+				t.Errorf("%s: got ident range %+v, want %+v", enc, def.IdentRange, want)
+			}
+		}
+		if !found {
+			t.Errorf("%s: no def X", enc)
+		}
+		for _, ref := range output.Refs {
+			if ref.Range == nil {
+				t.Errorf("%s: ref at %v has no range", enc, ref.Span)
+			}
+		}
+	}
+}
+
+func TestPositionEncodingsInMemory(t *testing.T) {
+	src := `package foo
+
+//line parser.y:10
+var s = "😀"; var X int
+`
+	prog := createPkg(t, "foo", []string{src}, []string{"y.go"})
+	pkgInfo := prog.Created[0]
+	opt := Options{PositionEncoding: PositionEncodingUTF16, Sources: map[string][]byte{"y.go": []byte(src)}}
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, opt)
+
+	// X is after a 4-byte character (😀, 2 UTF-16 code units) and 16
+	// 1-byte characters, on line 3 of y.go (the //line directive
+	// doesn't apply to ranges, which are in the same file as spans).
+	want := &definfo.Range{Start: definfo.Position{Line: 3, Character: 18}, End: definfo.Position{Line: 3, Character: 19}}
+	var found bool
+	for _, def := range output.Defs {
+		if def.Name != "X" {
+			continue
+		}
+		found = true
+		if def.File != "parser.y" {
+			t.Errorf("got file %q, want parser.y", def.File)
+		}
+		if def.IdentRange == nil || *def.IdentRange != *want {
+			t.Errorf("got ident range %+v, want %+v", def.IdentRange, want)
+		}
+	}
+	if !found {
+		t.Error("no def X")
+	}
+}
@@ -0,0 +1,357 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// ProtoFile is a .proto file that Go files generated by protoc-gen-go
+// (or protoc-gen-go-grpc) in the package being graphed were generated
+// from.
+type ProtoFile struct {
+	// Source is the name of the .proto file as it appears in the
+	// "// source: " comment of the files generated from it (see
+	// ProtoSource).
+	Source string
+
+	// Package is the .proto file's package (e.g., "foo.v1").
+	Package string
+
+	Symbols []*ProtoSymbol
+}
+
+// ProtoSymbol is a message, field, oneof, enum, enum value, service or
+// rpc declared in a .proto file.
+type ProtoSymbol struct {
+	// Kind is the kind of declaration (definfo.ProtoMessage,
+	// definfo.ProtoField, etc.).
+	Kind string
+
+	// Name is the symbol's name relative to the .proto file's package,
+	// with the names of the messages, enums and services it is
+	// declared in (e.g., "Outer.Inner.field", or "Color.RED" for the
+	// value RED of the enum Color).
+	Name string
+
+	// Oneof is the name of the oneof that a field is declared in, if
+	// any.
+	Oneof string
+
+	File      string
+	IdentSpan [2]uint32
+	DeclSpan  [2]uint32
+}
+
+// protoSourceRx matches the comment in the header of files generated
+// by protoc-gen-go that names the .proto file they were generated
+// from.
+var protoSourceRx = regexp.MustCompile(`^// source: (\S+\.proto)$`)
+
+// ProtoSource returns the name of the .proto file that f was generated
+// from according to its "// source: " header comment, or the empty
+// string if f was not generated from a .proto file.
+func ProtoSource(f *ast.File) string {
+	if !IsGenerated(f) {
+		return ""
+	}
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if m := protoSourceRx.FindStringSubmatch(c.Text); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+// ScanProtoFile returns the declarations in src, the contents of the
+// .proto file filename, whose Go files name it source.
+func ScanProtoFile(filename, source string, src []byte) *ProtoFile {
+	s := &protoScanner{
+		file: &ProtoFile{Source: source},
+		name: filename,
+		toks: tokenizeC(blankCComments(string(src))),
+	}
+	s.body(nil, "", "")
+	return s.file
+}
+
+type protoScanner struct {
+	file *ProtoFile
+	name string
+	toks []cToken
+	i    int
+}
+
+func (s *protoScanner) add(kind string, scope []string, ident cToken, oneof string, declStart int) *ProtoSymbol {
+	sym := &ProtoSymbol{
+		Kind:      kind,
+		Name:      strings.Join(append(scope[:len(scope):len(scope)], ident.text), "."),
+		Oneof:     oneof,
+		File:      s.name,
+		IdentSpan: [2]uint32{uint32(ident.start), uint32(ident.end())},
+		DeclSpan:  [2]uint32{uint32(declStart), uint32(s.toks[s.i-1].end())},
+	}
+	s.file.Symbols = append(s.file.Symbols, sym)
+	return sym
+}
+
+// body scans the statements up to the "}" that ends the body of the
+// block of the given kind ("message", "enum", etc., or "" for the
+// file), which is named scope. Oneof is the name of the oneof being
+// scanned, if any.
+func (s *protoScanner) body(scope []string, kind, oneof string) {
+	for s.i < len(s.toks) {
+		t := s.toks[s.i]
+		switch {
+		case t.text == "}":
+			s.i++
+			return
+
+		case (t.text == "message" || t.text == "enum" || t.text == "service" || t.text == "oneof") &&
+			s.i+2 < len(s.toks) && s.toks[s.i+1].isIdent() && s.toks[s.i+2].text == "{":
+			ident := s.toks[s.i+1]
+			s.i += 3
+			n := len(s.file.Symbols)
+			if t.text == "oneof" {
+				// a oneof's fields are declared in its message's scope
+				s.body(scope, t.text, ident.text)
+			} else {
+				s.body(append(scope[:len(scope):len(scope)], ident.text), t.text, "")
+			}
+			sym := s.add(t.text, scope, ident, "", t.start)
+			// keep the symbols in source order
+			copy(s.file.Symbols[n+1:], s.file.Symbols[n:])
+			s.file.Symbols[n] = sym
+
+		case t.text == "package" && kind == "":
+			var pkg string
+			for s.i++; s.i < len(s.toks) && s.toks[s.i].text != ";"; s.i++ {
+				pkg += s.toks[s.i].text
+			}
+			s.file.Package = pkg
+			s.i++
+
+		case t.text == "rpc" && kind == "service" && s.i+1 < len(s.toks) && s.toks[s.i+1].isIdent():
+			ident := s.toks[s.i+1]
+			s.statement()
+			s.add(definfo.ProtoRPC, scope, ident, "", t.start)
+
+		case t.text == "syntax" || t.text == "edition" || t.text == "import" || t.text == "option" ||
+			t.text == "reserved" || t.text == "extensions" || t.text == "extend" || t.text == "package" || t.text == ";":
+			s.statement()
+
+		default:
+			// a field or enum value: the name is the identifier before
+			// the first "="
+			start := s.i
+			s.statement()
+			if kind != "message" && kind != "oneof" && kind != "enum" {
+				continue
+			}
+			for j := start + 1; j < s.i; j++ {
+				if s.toks[j].text == "=" {
+					if ident := s.toks[j-1]; ident.isIdent() {
+						k := definfo.ProtoField
+						if kind == "enum" {
+							k = definfo.ProtoEnumValue
+						}
+						s.add(k, scope, ident, oneof, t.start)
+					}
+					break
+				}
+			}
+		}
+	}
+}
+
+// statement skips to the end of the statement starting at s.i: its ";",
+// or the "}" that ends its block (as for an "extend" or a "group"
+// field), but not a "}" that ends the enclosing block.
+func (s *protoScanner) statement() {
+	depth := 0
+	for ; s.i < len(s.toks); s.i++ {
+		switch s.toks[s.i].text {
+		case "{":
+			depth++
+		case "}":
+			if depth == 0 {
+				return
+			}
+			depth--
+			if depth == 0 {
+				s.i++
+				return
+			}
+		case ";":
+			if depth == 0 {
+				s.i++
+				return
+			}
+		}
+	}
+}
+
+// protoCamelCase converts a .proto name (relative to the package) to
+// the Go identifier that protoc-gen-go generates for it: "foo_bar"
+// becomes "FooBar", and "Outer.Inner" becomes "Outer_Inner".
+func protoCamelCase(s string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isLower(s[i+1]):
+			// skip; the next letter is capitalized below
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+			// skip; the next letter is capitalized below
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isLower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// protoGoName is the name of a Go def generated from a ProtoSymbol: a
+// package-level def (Member == "") or a field or method of the named
+// type.
+type protoGoName struct {
+	Type, Member string
+}
+
+// protoGoNames returns the names of the Go defs that protoc-gen-go and
+// protoc-gen-go-grpc generate for sym, given the kinds of the symbols
+// in its file by name.
+func protoGoNames(sym *ProtoSymbol, kinds map[string]string) []protoGoName {
+	parent, name := "", sym.Name
+	if i := strings.LastIndex(sym.Name, "."); i != -1 {
+		parent, name = sym.Name[:i], sym.Name[i+1:]
+	}
+	switch sym.Kind {
+	case definfo.ProtoMessage, definfo.ProtoEnum:
+		return []protoGoName{{Type: protoCamelCase(sym.Name)}}
+	case definfo.ProtoField, definfo.ProtoOneof:
+		msg, field := protoCamelCase(parent), protoCamelCase(name)
+		if sym.Oneof != "" {
+			return []protoGoName{{msg + "_" + field, field}, {msg, "Get" + field}}
+		}
+		return []protoGoName{{msg, field}, {msg, "Get" + field}}
+	case definfo.ProtoEnumValue:
+		// values of enums nested in a message are prefixed with the
+		// message's name, not the enum's
+		prefix := protoCamelCase(parent)
+		if i := strings.LastIndex(parent, "."); i != -1 && kinds[parent[:i]] == definfo.ProtoMessage {
+			prefix = protoCamelCase(parent[:i])
+		}
+		return []protoGoName{{Type: prefix + "_" + name}}
+	case definfo.ProtoService:
+		svc := protoCamelCase(sym.Name)
+		return []protoGoName{{Type: svc + "Client"}, {Type: svc + "Server"}}
+	case definfo.ProtoRPC:
+		svc, method := protoCamelCase(parent), protoCamelCase(name)
+		return []protoGoName{{svc + "Client", method}, {svc + "Server", method}}
+	}
+	return nil
+}
+
+// lookupProtoGoName returns the Go def in the package being graphed
+// that n names, or nil if there is none.
+func (g *grapher) lookupProtoGoName(n protoGoName) types.Object {
+	obj := g.typesPkg.Scope().Lookup(n.Type)
+	if n.Member == "" || obj == nil {
+		return obj
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil
+	}
+	m, _, _ := types.LookupFieldOrMethod(obj.Type(), true, g.typesPkg, n.Member)
+	return m
+}
+
+// protoSymbolPrefix is the first element of the paths of the defs for
+// .proto declarations. It contains a "$" so that it can't collide with
+// the path of a Go def (e.g., a field of a type named proto).
+const protoSymbolPrefix = "proto$"
+
+// emitProtoSymbols emits defs for the declarations in the .proto files
+// (in Options.ProtoFiles) that files were generated from, and relations
+// from the Go defs generated from them to those defs.
+func (g *grapher) emitProtoSymbols(files []*ast.File) []*Relation {
+	sources := make(map[string]bool)
+	for _, f := range files {
+		if src := ProtoSource(f); src != "" {
+			sources[src] = true
+		}
+	}
+
+	var rels []*Relation
+	for _, pf := range g.opt.ProtoFiles {
+		if !sources[pf.Source] {
+			continue
+		}
+		kinds := make(map[string]string, len(pf.Symbols))
+		for _, sym := range pf.Symbols {
+			kinds[sym.Name] = sym.Kind
+		}
+		for _, sym := range pf.Symbols {
+			key := &DefKey{PackageImportPath: g.typesPkg.Path(), Path: append([]string{protoSymbolPrefix}, strings.Split(sym.Name, ".")...)}
+			protoName := sym.Name
+			if pf.Package != "" {
+				protoName = pf.Package + "." + sym.Name
+			}
+			g.output.Defs = append(g.output.Defs, &Def{
+				Name:   sym.Name[strings.LastIndex(sym.Name, ".")+1:],
+				DefKey: key,
+
+				File:      sym.File,
+				IdentSpan: sym.IdentSpan,
+				DeclSpan:  sym.DeclSpan,
+
+				HeaderSpan: sym.DeclSpan,
+				FullSpan:   sym.DeclSpan,
+
+				DefInfo: definfo.DefInfo{
+					PkgScope:  true,
+					PkgName:   g.typesPkg.Name(),
+					Kind:      definfo.ProtoSymbol,
+					ProtoKind: sym.Kind,
+					ProtoName: protoName,
+				},
+			})
+			g.output.Refs = append(g.output.Refs, &Ref{
+				Unit:  g.typesPkg.Path(),
+				File:  sym.File,
+				Span:  sym.IdentSpan,
+				Def:   key,
+				IsDef: true,
+			})
+
+			for _, n := range protoGoNames(sym, kinds) {
+				if obj := g.lookupProtoGoName(n); obj != nil && obj.Pkg() == g.typesPkg {
+					from, _ := g.defInfo(obj)
+					rels = append(rels, &Relation{Kind: RelationProtoSource, From: from, To: key})
+				}
+			}
+		}
+	}
+	return rels
+}
@@ -0,0 +1,198 @@
+package gog
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScanProtoFile(t *testing.T) {
+	src := `syntax = "proto3";
+
+// Package comment with message Fake { }.
+package foo.v1;
+
+import "google/protobuf/timestamp.proto";
+option go_package = "example.com/foo";
+
+message Request {
+  option deprecated = true;
+  string user_id = 1 [json_name = "uid"];
+  map<string, int32> counts = 2;
+  message Filter { repeated string tags = 1; }
+  enum Order {
+    ORDER_UNSPECIFIED = 0;
+    ASC = 1;
+  }
+  oneof target {
+    string name = 3;
+    Filter filter = 4;
+  }
+  reserved 5, 6;
+}
+
+enum Color { RED = 0; }
+
+service Users {
+  rpc Get(Request) returns (Request);
+  rpc Watch(Request) returns (stream Request) { option idempotency_level = NO_SIDE_EFFECTS; }
+}
+`
+	pf := ScanProtoFile("foo.proto", "api/foo.proto", []byte(src))
+	if pf.Package != "foo.v1" {
+		t.Errorf("got package %q, want %q", pf.Package, "foo.v1")
+	}
+	var got []string
+	for _, sym := range pf.Symbols {
+		s := sym.Kind + " " + sym.Name
+		if sym.Oneof != "" {
+			s += " (" + sym.Oneof + ")"
+		}
+		if ident := src[sym.IdentSpan[0]:sym.IdentSpan[1]]; !strings.HasSuffix(sym.Name, ident) {
+			t.Errorf("%s: got ident %q", sym.Name, ident)
+		}
+		if decl := src[sym.DeclSpan[0]:sym.DeclSpan[1]]; !strings.HasSuffix(decl, ";") && !strings.HasSuffix(decl, "}") {
+			t.Errorf("%s: got decl %q", sym.Name, decl)
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"message Request",
+		"field Request.user_id",
+		"field Request.counts",
+		"message Request.Filter",
+		"field Request.Filter.tags",
+		"enum Request.Order",
+		"enum value Request.Order.ORDER_UNSPECIFIED",
+		"enum value Request.Order.ASC",
+		"oneof Request.target",
+		"field Request.name (target)",
+		"field Request.filter (target)",
+		"enum Color",
+		"enum value Color.RED",
+		"service Users",
+		"rpc Users.Get",
+		"rpc Users.Watch",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestProtoCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"user_id":      "UserId",
+		"Request":      "Request",
+		"Outer.Inner":  "Outer_Inner",
+		"outer.inner":  "OuterInner",
+		"_foo":         "XFoo",
+		"foo_bar2_baz": "FooBar2Baz",
+	}
+	for in, want := range tests {
+		if got := protoCamelCase(in); got != want {
+			t.Errorf("protoCamelCase(%q): got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProtoSource(t *testing.T) {
+	protoSrc := `syntax = "proto3";
+package foo.v1;
+message Request {
+  string user_id = 1;
+  message Filter {
+    enum Order { ASC = 0; }
+  }
+  oneof target { string name = 2; }
+}
+enum Color { RED = 0; }
+service Users { rpc Get(Request) returns (Request); }
+`
+	goSrc := `// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: api/foo.proto
+
+package foo
+
+type Request struct {
+	UserId string
+	Target isRequest_Target
+}
+
+func (x *Request) GetUserId() string { return x.UserId }
+func (x *Request) GetName() string   { return "" }
+
+type isRequest_Target interface{ isRequest_Target() }
+
+type Request_Name struct{ Name string }
+
+func (*Request_Name) isRequest_Target() {}
+
+type Request_Filter struct{}
+
+type Request_Filter_Order int32
+
+const Request_Filter_ASC Request_Filter_Order = 0
+
+type Color int32
+
+const Color_RED Color = 0
+
+type UsersClient interface{ Get() }
+type UsersServer interface{ Get() }
+`
+	prog := createPkg(t, "foo", []string{goSrc}, []string{"foo.pb.go"})
+	pkgInfo := prog.Created[0]
+	pf := ScanProtoFile("api/foo.proto", "api/foo.proto", []byte(protoSrc))
+
+	// the .proto file isn't used unless a generated file names it
+	opt := Options{ProtoFiles: []*ProtoFile{{Source: "api/other.proto", Symbols: pf.Symbols}}}
+	if output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, opt); len(output.Relations) != 0 {
+		t.Errorf("got %d relations for an unrelated .proto file, want none", len(output.Relations))
+	}
+
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{ProtoFiles: []*ProtoFile{pf}})
+	protoDefs := map[string]*Def{}
+	for _, def := range output.Defs {
+		if def.Kind == "proto" {
+			protoDefs[strings.Join(def.Path, "/")] = def
+		}
+	}
+	if def := protoDefs["proto$/Request/user_id"]; def == nil {
+		t.Fatal("no def for Request.user_id")
+	} else if def.ProtoKind != "field" || def.ProtoName != "foo.v1.Request.user_id" || def.File != "api/foo.proto" {
+		t.Errorf("got def %+v", def)
+	}
+
+	var rels []string
+	for _, rel := range output.Relations {
+		if rel.Kind != RelationProtoSource {
+			continue
+		}
+		if protoDefs[strings.Join(rel.To.Path, "/")] == nil {
+			t.Errorf("relation to %v, which is not a def", rel.To.Path)
+		}
+		rels = append(rels, strings.Join(rel.From.Path, "/")+" -> "+strings.Join(rel.To.Path[1:], "."))
+	}
+	sort.Strings(rels)
+	want := []string{
+		"Color -> Color",
+		"Color_RED -> Color.RED",
+		"Request -> Request",
+		"Request/GetName -> Request.name",
+		"Request/GetUserId -> Request.user_id",
+		"Request/Target -> Request.target",
+		"Request/UserId -> Request.user_id",
+		"Request_Filter -> Request.Filter",
+		"Request_Filter_ASC -> Request.Filter.Order.ASC",
+		"Request_Filter_Order -> Request.Filter.Order",
+		"Request_Name/Name -> Request.name",
+		"UsersClient -> Users",
+		"UsersClient/Get -> Users.Get",
+		"UsersServer -> Users",
+		"UsersServer/Get -> Users.Get",
+	}
+	if !reflect.DeepEqual(rels, want) {
+		t.Errorf("got relations\n%s\nwant\n%s", strings.Join(rels, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"go/ast"
 
 	"go/types"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
 )
 
 func (g *grapher) NewRef(node ast.Node, obj types.Object, pkgPath string) *Ref {
@@ -15,6 +17,8 @@ func (g *grapher) NewRef(node ast.Node, obj types.Object, pkgPath string) *Ref {
 		File: pos.Filename,
 		Span: makeSpan(g.fset, node),
 		Def:  key,
+
+		Deprecated: g.deprecation(obj, key) != "",
 	}
 }
 
@@ -24,7 +28,35 @@ type Ref struct {
 	Span [2]uint32
 	Def  *DefKey
 
+	// Range is the line/character range of Span, if
+	// Options.PositionEncoding is set.
+	Range *definfo.Range `json:",omitempty"`
+
 	// IsDef is true if ref is to the definition of Def, and false if it's to a
 	// use of Def.
 	IsDef bool
+
+	// Implicit is whether the ref is not written in the source but
+	// implied by another ref: a promoted field or method selector
+	// (e.g., x.Foo, where Foo is declared by x's embedded field Bar)
+	// implicitly refers to each embedded field it is reached
+	// through (Bar).
+	Implicit bool `json:",omitempty"`
+
+	// Via is the chain of embedded fields (outermost first) traversed
+	// to reach Def, if the ref is to a promoted field or method.
+	Via []*DefKey `json:",omitempty"`
+
+	// LowConfidence is whether the ref is in a file excluded from the
+	// build, so Def may be wrong (see Options.Excluded).
+	LowConfidence bool `json:",omitempty"`
+
+	// Generated is whether the ref is in a generated file (see
+	// IsGenerated).
+	Generated bool `json:",omitempty"`
+
+	// Deprecated is whether Def is deprecated (i.e., its doc comment
+	// has a "Deprecated: " paragraph) and the ref is a use of it (not
+	// IsDef).
+	Deprecated bool `json:",omitempty"`
 }
@@ -0,0 +1,50 @@
+package gog
+
+// Relation is a directed relationship between two defs that is not
+// expressed by a ref (e.g., a test and the def it exercises).
+type Relation struct {
+	Kind string
+
+	From *DefKey
+	To   *DefKey
+}
+
+// Kinds of relations.
+const (
+	// RelationTests relates a test, benchmark or fuzz target to the
+	// def it is named after (e.g., TestFoo to Foo, or TestT_M to
+	// method M of T).
+	RelationTests = "tests"
+
+	// RelationTestUses relates a test, benchmark or fuzz target to
+	// the package-level defs of the package under test that its body
+	// refers to.
+	RelationTestUses = "test-uses"
+
+	// RelationPromotes relates a struct type to a field or method of
+	// one of its (directly or indirectly) embedded fields that it
+	// promotes.
+	RelationPromotes = "promotes"
+
+	// RelationOverrides relates a method declared on a struct type to
+	// a method of the same name of one of its embedded fields, which
+	// would otherwise be promoted.
+	RelationOverrides = "overrides"
+
+	// RelationEmbeds relates an interface type to a named interface
+	// type that it embeds.
+	RelationEmbeds = "embeds"
+
+	// RelationProtoSource relates a Go def generated by protoc-gen-go
+	// (or protoc-gen-go-grpc) to the .proto declaration it was
+	// generated from.
+	RelationProtoSource = "proto-source"
+
+	// RelationInitUses relates a package-level var to the package-level
+	// vars and the funcs and methods that its initializer refers to,
+	// and an init func to those that its body refers to (i.e., those
+	// that are used at import time). The uses of blank vars' (e.g.,
+	// `var _ = register(...)`) initializers are related to the package
+	// def.
+	RelationInitUses = "init-uses"
+)
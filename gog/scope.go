@@ -232,8 +232,13 @@ func (g *grapher) assignMethodPaths(named *types.Named, prefix []string, pkgscop
 	}
 
 	if iface, ok := named.Underlying().(*types.Interface); ok {
+		// Only the explicitly declared methods are ours to assign
+		// paths to. (Indexing the whole method set, which is sorted
+		// and includes the methods of embedded interfaces, would
+		// assign an embedded method, possibly from another package,
+		// a path under this interface and skip an explicit one.)
 		for i := 0; i < iface.NumExplicitMethods(); i++ {
-			m := iface.Method(i)
+			m := iface.ExplicitMethod(i)
 			path := append(append([]string{}, prefix...), m.Name())
 			g.addPath(m, path)
 
@@ -263,7 +268,7 @@ func (g *grapher) assignStructFieldPaths(styp *types.Struct, prefix []string, pk
 }
 
 func (g *grapher) pathEnclosingInterval(start, end token.Pos) ([]ast.Node, bool) {
-	for _, f := range g.files {
+	for _, f := range g.checked {
 		if f.Pos() == token.NoPos || !tokenFileContainsPos(g.fset.File(f.Pos()), start) {
 			continue
 		}
@@ -286,6 +291,17 @@ func (g *grapher) addPath(obj types.Object, path []string) {
 	g.usedPaths[strings.Join(path, "/")] = struct{}{}
 }
 
+// inFiles reports whether pos is in one of the files being graphed (and
+// not in Options.PackageFiles).
+func (g *grapher) inFiles(pos token.Pos) bool {
+	for _, f := range g.files {
+		if f.Pos() != token.NoPos && tokenFileContainsPos(g.fset.File(f.Pos()), pos) {
+			return true
+		}
+	}
+	return false
+}
+
 func tokenFileContainsPos(f *token.File, pos token.Pos) bool {
 	p := int(pos)
 	base := f.Base()
@@ -0,0 +1,164 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// signature returns the structured form of sig.
+func (g *grapher) signature(sig *types.Signature) *definfo.Signature {
+	s := &definfo.Signature{
+		Params:   g.vars(sig.Params()),
+		Results:  g.vars(sig.Results()),
+		Variadic: sig.Variadic(),
+	}
+	if recv := sig.Recv(); recv != nil && recv.Type() != nil {
+		r := &definfo.Receiver{VarInfo: g.varInfo(recv)}
+		_, r.Pointer = recv.Type().(*types.Pointer)
+		s.Recv = r
+	}
+	return s
+}
+
+func (g *grapher) vars(t *types.Tuple) []definfo.VarInfo {
+	if t.Len() == 0 {
+		return nil
+	}
+	vars := make([]definfo.VarInfo, t.Len())
+	for i := range vars {
+		vars[i] = g.varInfo(t.At(i))
+	}
+	return vars
+}
+
+func (g *grapher) varInfo(v *types.Var) definfo.VarInfo {
+	name := v.Name()
+	if name == "_" {
+		name = ""
+	}
+	return definfo.VarInfo{
+		Name:    name,
+		Type:    v.Type().String(),
+		TypeDef: g.typeDefKey(v.Type()),
+	}
+}
+
+// typeDefKey returns the key of the named (or predeclared) type that
+// t refers to, after removing any pointer, slice, array or channel
+// type constructors. It returns nil if there is no such type.
+func (g *grapher) typeDefKey(t types.Type) *definfo.DefKey {
+	for {
+		switch tt := types.Unalias(t).(type) {
+		case *types.Pointer:
+			t = tt.Elem()
+			continue
+		case *types.Slice:
+			t = tt.Elem()
+			continue
+		case *types.Array:
+			t = tt.Elem()
+			continue
+		case *types.Chan:
+			t = tt.Elem()
+			continue
+		case *types.Named:
+			return g.objDefKey(tt.Obj())
+		case *types.Basic:
+			if obj := types.Universe.Lookup(tt.Name()); obj != nil {
+				return g.objDefKey(obj)
+			}
+		}
+		return nil
+	}
+}
+
+// objDefKey returns the key of obj's def, in the form used in
+// definfo.
+func (g *grapher) objDefKey(obj types.Object) *definfo.DefKey {
+	key, _ := g.defInfo(obj)
+	return &definfo.DefKey{PackageImportPath: key.PackageImportPath, Path: key.Path}
+}
+
+// members returns the fields of t (if it is a struct type) and its
+// methods: the explicit methods and embedded interfaces of an
+// interface type, or the methods declared on a named non-interface
+// type. Both are in declaration order. DeclNode is the declaration of
+// t, from which the order of an interface's methods is taken (go/types
+// sorts the explicit methods and keeps the embedded interfaces apart).
+func (g *grapher) members(t types.Type, declNode ast.Node) (fields, methods []definfo.Member) {
+	named, _ := t.(*types.Named)
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			fields = append(fields, definfo.Member{
+				VarInfo:  g.varInfo(f),
+				Def:      g.objDefKey(f),
+				Embedded: f.Anonymous(),
+				Tag:      u.Tag(i),
+			})
+		}
+	case *types.Interface:
+		explicit := make(map[string]*types.Func, u.NumExplicitMethods())
+		for i := 0; i < u.NumExplicitMethods(); i++ {
+			explicit[u.ExplicitMethod(i).Name()] = u.ExplicitMethod(i)
+		}
+		embedded := func(i int) definfo.Member {
+			et := u.EmbeddedType(i)
+			return definfo.Member{
+				VarInfo:  definfo.VarInfo{Type: et.String(), TypeDef: g.typeDefKey(et)},
+				Embedded: true,
+			}
+		}
+
+		// The embedded interfaces are in declaration order among
+		// themselves, so they are interleaved with the explicit
+		// methods as they appear in the source.
+		var nEmbedded int
+		if ts, ok := declNode.(*ast.TypeSpec); ok {
+			if it, ok := ts.Type.(*ast.InterfaceType); ok {
+				for _, f := range it.Methods.List {
+					if len(f.Names) == 0 {
+						if nEmbedded < u.NumEmbeddeds() {
+							methods = append(methods, embedded(nEmbedded))
+							nEmbedded++
+						}
+						continue
+					}
+					for _, name := range f.Names {
+						if m := explicit[name.Name]; m != nil {
+							methods = append(methods, g.method(m))
+							delete(explicit, name.Name)
+						}
+					}
+				}
+			}
+		}
+		// any that the declaration doesn't show (e.g., for a type
+		// declared elsewhere), in go/types' order
+		for i := 0; i < u.NumExplicitMethods(); i++ {
+			if m := u.ExplicitMethod(i); explicit[m.Name()] != nil {
+				methods = append(methods, g.method(m))
+			}
+		}
+		for ; nEmbedded < u.NumEmbeddeds(); nEmbedded++ {
+			methods = append(methods, embedded(nEmbedded))
+		}
+		return fields, methods
+	}
+	if named != nil {
+		for i := 0; i < named.NumMethods(); i++ {
+			methods = append(methods, g.method(named.Method(i)))
+		}
+	}
+	return fields, methods
+}
+
+func (g *grapher) method(m *types.Func) definfo.Member {
+	return definfo.Member{
+		VarInfo: definfo.VarInfo{Name: m.Name(), Type: m.Type().String()},
+		Def:     g.objDefKey(m),
+	}
+}
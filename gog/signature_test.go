@@ -0,0 +1,70 @@
+package gog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestSignatures(t *testing.T) {
+	src := `package foo
+
+import "io"
+
+type T struct {
+	A int
+	io.Reader
+	b []*T
+}
+
+func (t *T) M(x, y int, rest ...string) (n int, err error) { return }
+
+type I interface {
+	N() T
+	io.Closer
+}
+
+type J interface {
+	Z()
+	io.Reader
+	A()
+}
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	want := map[string]string{
+		"T/M": `{"Signature":{"Recv":{"Name":"t","Type":"*foo.T","TypeDef":{"PackageImportPath":"foo","Path":["T"]},"Pointer":true},` +
+			`"Params":[{"Name":"x","Type":"int","TypeDef":{"PackageImportPath":"builtin","Path":["int"]}},{"Name":"y","Type":"int","TypeDef":{"PackageImportPath":"builtin","Path":["int"]}},{"Name":"rest","Type":"[]string","TypeDef":{"PackageImportPath":"builtin","Path":["string"]}}],` +
+			`"Results":[{"Name":"n","Type":"int","TypeDef":{"PackageImportPath":"builtin","Path":["int"]}},{"Name":"err","Type":"error","TypeDef":{"PackageImportPath":"builtin","Path":["error"]}}],"Variadic":true}}`,
+		"T": `{"Fields":[{"Name":"A","Type":"int","TypeDef":{"PackageImportPath":"builtin","Path":["int"]},"Def":{"PackageImportPath":"foo","Path":["T","A"]}},` +
+			`{"Name":"Reader","Type":"io.Reader","TypeDef":{"PackageImportPath":"io","Path":["Reader"]},"Def":{"PackageImportPath":"foo","Path":["T","Reader"]},"Embedded":true},` +
+			`{"Name":"b","Type":"[]*foo.T","TypeDef":{"PackageImportPath":"foo","Path":["T"]},"Def":{"PackageImportPath":"foo","Path":["T","b"]}}],` +
+			`"Methods":[{"Name":"M","Type":"func(x int, y int, rest ...string) (n int, err error)","Def":{"PackageImportPath":"foo","Path":["T","M"]}}]}`,
+		"I": `{"Methods":[{"Name":"N","Type":"func() foo.T","Def":{"PackageImportPath":"foo","Path":["I","N"]}},` +
+			`{"Type":"io.Closer","TypeDef":{"PackageImportPath":"io","Path":["Closer"]},"Embedded":true}]}`,
+		"J": `{"Methods":[{"Name":"Z","Type":"func()","Def":{"PackageImportPath":"foo","Path":["J","Z"]}},` +
+			`{"Type":"io.Reader","TypeDef":{"PackageImportPath":"io","Path":["Reader"]},"Embedded":true},` +
+			`{"Name":"A","Type":"func()","Def":{"PackageImportPath":"foo","Path":["J","A"]}}]}`,
+	}
+	for _, def := range output.Defs {
+		w, ok := want[strings.Join(def.Path, "/")]
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(struct {
+			Signature *definfo.Signature `json:",omitempty"`
+			Fields    []definfo.Member   `json:",omitempty"`
+			Methods   []definfo.Member   `json:",omitempty"`
+		}{def.Signature, def.Fields, def.Methods})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != w {
+			t.Errorf("%s: got\n%s\nwant\n%s", strings.Join(def.Path, "/"), b, w)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gog
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// declSpans returns the spans of the header (e.g., a func's signature
+// or "type T struct"), body (e.g., a func's body or a struct's field
+// list) and full declaration (including its doc and line comments and,
+// for a single-spec declaration, the keyword) of the def declared by
+// declNode. The body span is zero if there is no body.
+func (g *grapher) declSpans(declNode ast.Node) (header, body, full [2]uint32) {
+	start, end := declNode.Pos(), declNode.End()
+	headerEnd := end
+	var bodyNode ast.Node
+	var doc, comment *ast.CommentGroup
+
+	switch n := declNode.(type) {
+	case *ast.FuncDecl:
+		headerEnd = n.Type.End()
+		if n.Body != nil {
+			bodyNode = n.Body
+		}
+		doc = n.Doc
+
+	case *ast.TypeSpec:
+		switch t := n.Type.(type) {
+		case *ast.StructType:
+			headerEnd = t.Struct + token.Pos(len("struct"))
+			bodyNode = t.Fields
+		case *ast.InterfaceType:
+			headerEnd = t.Interface + token.Pos(len("interface"))
+			bodyNode = t.Methods
+		}
+		doc, comment = n.Doc, n.Comment
+		if gd := g.genDecls[n]; gd != nil && !gd.Lparen.IsValid() {
+			start, doc = gd.Pos(), gd.Doc
+		}
+
+	case *ast.ValueSpec:
+		doc, comment = n.Doc, n.Comment
+		if gd := g.genDecls[n]; gd != nil && !gd.Lparen.IsValid() {
+			start, doc = gd.Pos(), gd.Doc
+		}
+
+	case *ast.ImportSpec:
+		doc, comment = n.Doc, n.Comment
+		if gd := g.genDecls[n]; gd != nil && !gd.Lparen.IsValid() {
+			start, doc = gd.Pos(), gd.Doc
+		}
+
+	case *ast.Field:
+		doc, comment = n.Doc, n.Comment
+
+	case *ast.RangeStmt:
+		headerEnd = n.X.End()
+		bodyNode = n.Body
+	}
+
+	header = makeRange(g.fset, start, headerEnd)
+	if bodyNode != nil {
+		body = makeSpan(g.fset, bodyNode)
+	}
+	if doc != nil {
+		start = doc.Pos()
+	}
+	if comment != nil && comment.End() > end {
+		end = comment.End()
+	}
+	full = makeRange(g.fset, start, end)
+	return header, body, full
+}
+
+// makeRange returns the span of the source between pos and end.
+func makeRange(fset *token.FileSet, pos, end token.Pos) [2]uint32 {
+	start := fset.Position(pos)
+	return [2]uint32{uint32(start.Offset), uint32(start.Offset + int(end-pos))}
+}
@@ -0,0 +1,64 @@
+package gog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeclSpans(t *testing.T) {
+	src := `package foo
+
+// F does things.
+func F(x int) error {
+	return nil
+}
+
+// T is a type.
+type T struct {
+	// A is a field.
+	A int // line comment
+}
+
+var (
+	// V is a var.
+	V = 1 // one
+)
+
+// W is a var.
+var W int
+
+type I interface{ M() }
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	text := func(span [2]uint32) string {
+		return src[span[0]:span[1]]
+	}
+	type spans struct{ header, body, full string }
+	want := map[string]spans{
+		"F":   {"func F(x int) error", "{\n\treturn nil\n}", "// F does things.\nfunc F(x int) error {\n\treturn nil\n}"},
+		"T":   {"type T struct", "{\n\t// A is a field.\n\tA int // line comment\n}", "// T is a type.\ntype T struct {\n\t// A is a field.\n\tA int // line comment\n}"},
+		"T/A": {"A int", "", "// A is a field.\n\tA int // line comment"},
+		"V":   {"V = 1", "", "// V is a var.\n\tV = 1 // one"},
+		"W":   {"var W int", "", "// W is a var.\nvar W int"},
+		"I":   {"type I interface", "{ M() }", "type I interface{ M() }"},
+		"I/M": {"M()", "", "M()"},
+	}
+	for _, def := range output.Defs {
+		path := strings.Join(def.Path, "/")
+		w, ok := want[path]
+		if !ok {
+			continue
+		}
+		delete(want, path)
+		got := spans{text(def.HeaderSpan), text(def.BodySpan), text(def.FullSpan)}
+		if got != w {
+			t.Errorf("%s: got spans %q, want %q", path, got, w)
+		}
+	}
+	for path := range want {
+		t.Errorf("no def %s", path)
+	}
+}
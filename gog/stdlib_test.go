@@ -68,7 +68,7 @@ func testPkg(t *testing.T, path string) {
 	start := time.Now()
 	var output Output
 	for _, pkgInfo := range prog.AllPackages {
-		o := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, true)
+		o := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
 		output.Append(o)
 
 	}
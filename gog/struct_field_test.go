@@ -77,7 +77,7 @@ func TestResolveStructFields(t *testing.T) {
 		prog := createPkg(t, "foo", []string{src}, nil)
 
 		pkgInfo := prog.Created[0]
-		output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, false)
+		output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
 
 		var refs []*Ref
 		for _, r := range output.Refs {
@@ -0,0 +1,139 @@
+package gog
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// fieldTag returns the (unquoted) tag of field, or the empty string
+// if it has none.
+func fieldTag(field *ast.Field) string {
+	if field == nil || field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return tag
+}
+
+// parseTag parses a struct tag in the conventional format (a list of
+// space-separated key:"value" pairs, as understood by
+// reflect.StructTag.Get) into its keys. Parsing stops at the first
+// malformed pair.
+func parseTag(tag string) []definfo.TagKey {
+	var keys []definfo.TagKey
+	for tag != "" {
+		// This loop follows reflect.StructTag.Lookup.
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		tag = tag[i+1:]
+
+		keys = append(keys, parseTagValue(key, value))
+	}
+	return keys
+}
+
+// parseTagValue splits the value of the tag key into a name and
+// options. For most keys (json, yaml, xml, db, etc.) the name is the
+// first comma-separated element; for protobuf tags it is given by the
+// "name=" option.
+func parseTagValue(key, value string) definfo.TagKey {
+	tk := definfo.TagKey{Key: key, Value: value}
+	parts := strings.Split(value, ",")
+	if key == "protobuf" {
+		tk.Options = parts
+		for _, p := range parts {
+			if strings.HasPrefix(p, "name=") {
+				tk.Name = strings.TrimPrefix(p, "name=")
+			}
+		}
+		return tk
+	}
+	tk.Name = parts[0]
+	if len(parts) > 1 {
+		tk.Options = parts[1:]
+	}
+	return tk
+}
+
+// FieldsBySerializedName returns the field defs in defs that are
+// serialized as name according to their tags. If key is nonempty, only
+// that tag key (e.g., "json") is considered. A field whose tag value
+// for a key is "-" is not serialized with that key. As with
+// encoding/json, an exported field that has no name in its json tag
+// (and is not embedded) is serialized as its Go name.
+func FieldsBySerializedName(defs []*Def, key, name string) []*Def {
+	// embedded fields are flattened by encoding/json, not named
+	embedded := make(map[string]bool)
+	for _, def := range defs {
+		for _, f := range def.Fields {
+			if f.Embedded && f.Def != nil {
+				embedded[(&DefKey{f.Def.PackageImportPath, f.Def.Path}).String()] = true
+			}
+		}
+	}
+
+	var fields []*Def
+	for _, def := range defs {
+		if def.Kind == definfo.Field && serializedAs(def, key, name, embedded[def.DefKey.String()]) {
+			fields = append(fields, def)
+		}
+	}
+	return fields
+}
+
+// serializedAs reports whether the field def is serialized as name
+// according to its tags (see FieldsBySerializedName).
+func serializedAs(def *Def, key, name string, embedded bool) bool {
+	var jsonNamed bool
+	for _, tk := range def.TagKeys {
+		if key != "" && tk.Key != key {
+			continue
+		}
+		if tk.Key == "json" {
+			jsonNamed = tk.Name != "" || tk.Value == "-"
+		}
+		if tk.Value == "-" {
+			continue
+		}
+		if tk.Name == name {
+			return true
+		}
+	}
+	return (key == "" || key == "json") && !jsonNamed && def.Exported && !embedded && def.Name == name
+}
@@ -0,0 +1,99 @@
+package gog
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := map[string][]definfo.TagKey{
+		``:                  nil,
+		`json:"id"`:         {{Key: "json", Value: "id", Name: "id"}},
+		`json:",omitempty"`: {{Key: "json", Value: ",omitempty", Options: []string{"omitempty"}}},
+		`json:"user_id,omitempty,string" db:"user_id"`: {
+			{Key: "json", Value: "user_id,omitempty,string", Name: "user_id", Options: []string{"omitempty", "string"}},
+			{Key: "db", Value: "user_id", Name: "user_id"},
+		},
+		`protobuf:"varint,1,opt,name=user_id,json=userId"`: {
+			{Key: "protobuf", Value: "varint,1,opt,name=user_id,json=userId", Name: "user_id", Options: []string{"varint", "1", "opt", "name=user_id", "json=userId"}},
+		},
+		`xml:"a\"b"  yaml:"c"`: {
+			{Key: "xml", Value: `a"b`, Name: `a"b`},
+			{Key: "yaml", Value: "c", Name: "c"},
+		},
+		`json:"a" malformed yaml:"b"`: {{Key: "json", Value: "a", Name: "a"}},
+	}
+	for tag, want := range tests {
+		if got := parseTag(tag); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %+v, want %+v", tag, got, want)
+		}
+	}
+}
+
+func TestFieldsBySerializedName(t *testing.T) {
+	src := `package foo
+
+type User struct {
+	ID   int    ` + "`json:\"user_id\" db:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type Row struct {
+	UserID int ` + "`db:\"user_id\"`" + `
+	Secret string ` + "`json:\"-\" yaml:\"-\"`" + `
+	Dash   string ` + "`json:\"-,\"`" + `
+	Email  string ` + "`json:\",omitempty\"`" + `
+	User
+	internal int
+}
+`
+	prog := createPkg(t, "foo", []string{src}, nil)
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	for _, def := range output.Defs {
+		if strings.Join(def.Path, "/") == "User/ID" && def.Tag != `json:"user_id" db:"id"` {
+			t.Errorf("got tag %q for User/ID", def.Tag)
+		}
+		if strings.Join(def.Path, "/") == "User" && (len(def.Fields) != 2 || def.Fields[1].Tag != `json:"name"`) {
+			t.Errorf("got fields %+v for User", def.Fields)
+		}
+	}
+
+	tests := []struct {
+		key, name string
+		want      []string
+	}{
+		{"", "user_id", []string{"Row/UserID", "User/ID"}},
+		{"json", "user_id", []string{"User/ID"}},
+		{"db", "id", []string{"User/ID"}},
+		{"yaml", "user_id", nil},
+
+		// "-" skips the field, unless it is followed by a comma
+		{"", "-", []string{"Row/Dash"}},
+		{"json", "Secret", nil},
+
+		// exported fields without a json name are serialized as their
+		// Go name, unless they are embedded
+		{"json", "Email", []string{"Row/Email"}},
+		{"", "UserID", []string{"Row/UserID"}},
+		{"json", "Name", nil},
+		{"json", "User", nil},
+		{"json", "internal", nil},
+		{"db", "Email", nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, def := range FieldsBySerializedName(output.Defs, test.key, test.name) {
+			got = append(got, strings.Join(def.Path, "/"))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:%s: got %q, want %q", test.key, test.name, got, test.want)
+		}
+	}
+}
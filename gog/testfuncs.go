@@ -0,0 +1,210 @@
+package gog
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+// testFuncPrefixes maps the name prefixes of test functions to their
+// kinds and the type of their sole parameter (if any).
+var testFuncPrefixes = []struct {
+	prefix, kind, param string
+}{
+	{"Test", definfo.TestFunc, "*testing.T"},
+	{"Benchmark", definfo.BenchmarkFunc, "*testing.B"},
+	{"Fuzz", definfo.FuzzFunc, "*testing.F"},
+	{"Example", definfo.ExampleFunc, ""},
+}
+
+// testKind returns the kind of test function (definfo.TestFunc, etc.)
+// that fn, declared in filename, is, or the empty string if it is not
+// a test function. The rules are the same as the go tool's.
+func testKind(fn *types.Func, filename string) string {
+	if !strings.HasSuffix(filename, "_test.go") || fn.Parent() != fn.Pkg().Scope() {
+		return ""
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() != nil || sig.Results().Len() != 0 {
+		return ""
+	}
+	for _, p := range testFuncPrefixes {
+		if !isTestName(fn.Name(), p.prefix) {
+			continue
+		}
+		if p.param == "" {
+			if sig.Params().Len() == 0 {
+				return p.kind
+			}
+		} else if sig.Params().Len() == 1 && types.TypeString(sig.Params().At(0).Type(), nil) == p.param {
+			return p.kind
+		}
+		return ""
+	}
+	return ""
+}
+
+// isTestName reports whether name looks like a test (or benchmark,
+// etc., according to prefix): it starts with prefix, which is not
+// followed by a lower-case letter.
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// emitTestRelations relates each test, benchmark and fuzz target in
+// files to the def it is named after (RelationTests) and to the other
+// package-level defs of the package under test that it refers to
+// (RelationTestUses).
+func (g *grapher) emitTestRelations(files []*ast.File) []*Relation {
+	testedPkg := g.testedPkg()
+
+	var rels []*Relation
+	for _, f := range files {
+		filename := g.fset.Position(f.Package).Filename
+		if !strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			fn, ok := g.typesInfo.Defs[fd.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			kind := testKind(fn, filename)
+			if kind == "" || kind == definfo.ExampleFunc {
+				continue
+			}
+			from, _ := g.defInfo(fn)
+
+			subject := testSubject(fn.Name(), testedPkg)
+			if subject != nil && strings.HasSuffix(g.fset.Position(subject.Pos()).Filename, "_test.go") {
+				subject = nil
+			}
+			if subject != nil {
+				to := g.testedDefKey(subject, testedPkg)
+				rels = append(rels, &Relation{Kind: RelationTests, From: from, To: to})
+			}
+
+			seen := map[types.Object]struct{}{subject: {}}
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				ident, ok := n.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				obj := g.typesInfo.Uses[ident]
+				if obj == nil || obj.Pkg() != testedPkg || !isPkgLevel(obj) {
+					return true
+				}
+				if _, seen := seen[obj]; seen {
+					return true
+				}
+				seen[obj] = struct{}{}
+				if strings.HasSuffix(g.fset.Position(obj.Pos()).Filename, "_test.go") {
+					// test helpers are not under test
+					return true
+				}
+				to, _ := g.defInfo(obj)
+				rels = append(rels, &Relation{Kind: RelationTestUses, From: from, To: to})
+				return true
+			})
+		}
+	}
+	return rels
+}
+
+// isPkgLevel reports whether obj is a package-level const, func, type
+// or var, or a method.
+func isPkgLevel(obj types.Object) bool {
+	switch obj := obj.(type) {
+	case *types.Const, *types.TypeName, *types.Var:
+		return obj.Parent() != nil && obj.Parent() == obj.Pkg().Scope()
+	case *types.Func:
+		return obj.Type().(*types.Signature).Recv() != nil || obj.Parent() == obj.Pkg().Scope()
+	}
+	return false
+}
+
+// testSubject returns the def in pkg that the test function named
+// name is named after, following the usual conventions: TestFoo tests
+// Foo (or foo), TestT_M tests method M declared on type T (not one
+// promoted from its embedded fields), and any further "_suffix" is
+// ignored. It returns nil if there is no such def.
+func testSubject(name string, pkg *types.Package) types.Object {
+	for _, p := range testFuncPrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			name = name[len(p.prefix):]
+			break
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	lookup := func(id string) types.Object {
+		for _, id := range []string{id, lowerFirst(id)} {
+			if obj := pkg.Scope().Lookup(id); obj != nil {
+				return obj
+			}
+		}
+		return nil
+	}
+	parts := strings.Split(name, "_")
+	for n := len(parts); n >= 1; n-- {
+		// Try "T_M" as method M of type T before trying a def
+		// named "T_M".
+		if n >= 2 {
+			if tn, ok := lookup(strings.Join(parts[:n-1], "_")).(*types.TypeName); ok {
+				for _, m := range []string{parts[n-1], lowerFirst(parts[n-1])} {
+					obj, _, _ := types.LookupFieldOrMethod(tn.Type(), true, pkg, m)
+					if fn, ok := obj.(*types.Func); ok && recvTypeName(fn) == tn {
+						return fn
+					}
+				}
+			}
+		}
+		if obj := lookup(strings.Join(parts[:n], "_")); obj != nil && isPkgLevel(obj) {
+			return obj
+		}
+	}
+	return nil
+}
+
+// recvTypeName returns the type name of the receiver of the method fn
+// (the interface type's, for an interface method), or nil if it has
+// none.
+func recvTypeName(fn *types.Func) *types.TypeName {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return nil
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj()
+	}
+	return nil
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
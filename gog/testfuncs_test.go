@@ -0,0 +1,155 @@
+package gog
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+)
+
+func TestTestRelations(t *testing.T) {
+	sources := []string{
+		`package foo
+
+func Parse() {}
+
+func helper() int { return 0 }
+
+type T struct{}
+
+func (T) M() {}
+
+type U struct{ T }
+
+const C = 1
+`,
+		`package foo
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	Parse()
+	_ = helper()
+	_ = C
+	_ = testHelper()
+}
+
+func TestT_M_ptr(t *testing.T) {
+	var x T
+	x.M()
+}
+
+// M is only promoted to U, so this tests U.
+func TestU_M(t *testing.T) {}
+
+func BenchmarkHelper(b *testing.B) {}
+
+func FuzzNothing(f *testing.F) {}
+
+func Testing(t *testing.T) {}
+
+func ExampleT() {}
+
+func testHelper() int { return 0 }
+`,
+	}
+	prog := createPkg(t, "foo", sources, []string{"foo.go", "foo_test.go"})
+	pkgInfo := prog.Created[0]
+	output := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{})
+
+	var rels []string
+	for _, rel := range output.Relations {
+		rels = append(rels, strings.Join(rel.From.Path, "/")+" "+rel.Kind+" "+strings.Join(rel.To.Path, "/"))
+	}
+	sort.Strings(rels)
+	wantRels := []string{
+		"BenchmarkHelper tests helper",
+		"TestParse test-uses C",
+		"TestParse test-uses helper",
+		"TestParse tests Parse",
+		"TestT_M_ptr test-uses T",
+		"TestT_M_ptr tests T/M",
+		"TestU_M tests U",
+		"U promotes T/M",
+	}
+	if !reflect.DeepEqual(rels, wantRels) {
+		t.Errorf("got relations %q, want %q", rels, wantRels)
+	}
+
+	testKinds := make(map[string]string)
+	for _, def := range output.Defs {
+		if def.TestKind != "" {
+			testKinds[def.Name] = def.TestKind
+		}
+	}
+	wantTestKinds := map[string]string{
+		"TestParse":       definfo.TestFunc,
+		"TestT_M_ptr":     definfo.TestFunc,
+		"TestU_M":         definfo.TestFunc,
+		"BenchmarkHelper": definfo.BenchmarkFunc,
+		"FuzzNothing":     definfo.FuzzFunc,
+		"ExampleT":        definfo.ExampleFunc,
+	}
+	if !reflect.DeepEqual(testKinds, wantTestKinds) {
+		t.Errorf("got test kinds %v, want %v", testKinds, wantTestKinds)
+	}
+}
+
+func TestXTestRelations(t *testing.T) {
+	src := `package foo_test
+
+import (
+	"testing"
+
+	"foo"
+)
+
+func TestT_M(t *testing.T) {
+	var x foo.T
+	_ = x
+}
+
+func TestParse(t *testing.T) { foo.Parse() }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo_test.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := &fakeImporter{fset: fset, pkgs: map[string]*types.Package{}, srcs: map[string]string{
+		"testing": "package testing\ntype T struct{}\n",
+		"foo":     "package foo\nfunc Parse() {}\ntype T struct{}\nfunc (T) M() {}\n",
+	}}
+	info := &types.Info{
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	pkg, err := (&types.Config{Importer: imp}).Check("foo_test", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := Graph(fset, []*ast.File{f}, pkg, info, Options{})
+
+	var rels []string
+	for _, rel := range output.Relations {
+		if rel.Kind == RelationTests {
+			rels = append(rels, strings.Join(rel.From.Path, "/")+" tests "+rel.To.PackageImportPath+"#"+strings.Join(rel.To.Path, "/"))
+		}
+	}
+	sort.Strings(rels)
+	// M is not selected in the test, so its receiver comes from the
+	// package under test
+	want := []string{"TestParse tests foo#Parse", "TestT_M tests foo#T/M"}
+	if !reflect.DeepEqual(rels, want) {
+		t.Errorf("got relations %q, want %q", rels, want)
+	}
+}
@@ -3,6 +3,7 @@ package gog
 import (
 	"fmt"
 	"go/ast"
+	"go/parser"
 	"io/ioutil"
 	"testing"
 
@@ -13,7 +14,7 @@ func graphPkgFromFiles(t *testing.T, path string, filenames []string) (*Output,
 	prog := createPkgFromFiles(t, path, filenames)
 	var output Output
 	for _, pkgInfo := range prog.AllPackages {
-		o := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, true)
+		o := Graph(prog.Fset, pkgInfo.Files, pkgInfo.Pkg, &pkgInfo.Info, Options{IncludeDocs: true})
 		output.Append(o)
 	}
 	return &output, prog
@@ -33,6 +34,7 @@ func createPkgFromFiles(t *testing.T, path string, filenames []string) *loader.P
 
 func createPkg(t *testing.T, path string, sources []string, names []string) *loader.Program {
 	conf := Default
+	conf.ParserMode = parser.ParseComments
 
 	var files []*ast.File
 	for i, src := range sources {
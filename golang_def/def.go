@@ -18,6 +18,56 @@ type DefData struct {
 	// def (if this def is not a package). If this def is a package,
 	// PackageImportPath is its own import path.
 	PackageImportPath string `json:",omitempty"`
+
+	// Relations are this def's relations to other defs that are not
+	// expressed by refs (e.g., the defs that a test exercises).
+	Relations []DefRelation `json:",omitempty"`
+
+	// Spans are the byte offset spans of parts of this def's
+	// declaration in its file (if this def is not a package).
+	Spans *DefSpans `json:",omitempty"`
+
+	// Ranges are the line/character ranges of the def and the parts
+	// of its declaration, if a position encoding was requested.
+	Ranges *DefRanges `json:",omitempty"`
+}
+
+// DefSpans are the spans of parts of a def's declaration (see gog.Def).
+type DefSpans struct {
+	Ident  [2]uint32
+	Header [2]uint32
+
+	// Body is nil if the def has no body.
+	Body *[2]uint32 `json:",omitempty"`
+
+	// Full includes the doc comment.
+	Full [2]uint32
+}
+
+// DefRanges are the line/character ranges of a def (see DefSpans).
+type DefRanges struct {
+	Ident  *definfo.Range `json:",omitempty"`
+	Decl   *definfo.Range `json:",omitempty"`
+	Header *definfo.Range `json:",omitempty"`
+	Body   *definfo.Range `json:",omitempty"`
+	Full   *definfo.Range `json:",omitempty"`
+}
+
+// DefRelation is a relation from a def to another def (the target).
+type DefRelation struct {
+	// Kind is the kind of relation (see the gog.Relation* constants).
+	Kind string
+
+	DefTarget
+}
+
+// DefTarget identifies a def that is related to or referred to by
+// something other than a ref.
+type DefTarget struct {
+	DefRepo     string `json:",omitempty"`
+	DefUnitType string `json:",omitempty"`
+	DefUnit     string `json:",omitempty"`
+	DefPath     string
 }
 
 func init() {
@@ -57,6 +107,17 @@ func (f defFormatter) DefKeyword() string {
 		return "interface"
 	case definfo.Const:
 		return "const"
+	case definfo.ImportName:
+		return "import"
+	case definfo.CSymbol:
+		if f.info.CKind == definfo.CMacro {
+			return "#define"
+		}
+		return "C " + f.info.CKind
+	case definfo.AsmSymbol:
+		return f.info.AsmDirective
+	case definfo.ProtoSymbol:
+		return f.info.ProtoKind
 	}
 	return ""
 }
@@ -84,6 +145,12 @@ func (f defFormatter) Name(qual graph.Qualification) string {
 	if qual == graph.Unqualified {
 		return f.def.Name
 	}
+	if f.info.Kind == definfo.CSymbol {
+		return "C." + f.def.Name
+	}
+	if f.info.Kind == definfo.ProtoSymbol {
+		return f.info.ProtoName
+	}
 
 	var recvlike string
 	if f.info.Kind == definfo.Field {
@@ -137,9 +204,18 @@ func (f defFormatter) NameAndTypeSeparator() string {
 }
 
 func (f defFormatter) Type(qual graph.Qualification) string {
+	switch f.info.Kind {
+	case definfo.ImportName:
+		return ` "` + f.info.ImportedPackage + `"`
+	case definfo.CSymbol, definfo.AsmSymbol, definfo.ProtoSymbol:
+		return ""
+	}
 	var ts string
 	switch f.def.Kind {
 	case "func":
+		if sig := f.info.Signature; sig != nil {
+			return f.fmtSignature(sig, qual)
+		}
 		ts = f.info.TypeString
 		ts = strings.TrimPrefix(ts, "func")
 	case "type":
@@ -151,8 +227,40 @@ func (f defFormatter) Type(qual graph.Qualification) string {
 	default:
 		ts = " " + f.info.TypeString
 	}
+	return f.qualifyType(ts, qual)
+}
+
+// fmtSignature formats sig like the Type of a func or method, e.g.,
+// `(a int, b ...string) (c int, err error)`.
+func (f defFormatter) fmtSignature(sig *definfo.Signature, qual graph.Qualification) string {
+	s := "(" + f.fmtVars(sig.Params, sig.Variadic, qual) + ")"
+	switch {
+	case len(sig.Results) == 1 && sig.Results[0].Name == "":
+		s += " " + f.qualifyType(sig.Results[0].Type, qual)
+	case len(sig.Results) > 0:
+		s += " (" + f.fmtVars(sig.Results, false, qual) + ")"
+	}
+	return s
+}
+
+func (f defFormatter) fmtVars(vars []definfo.VarInfo, variadic bool, qual graph.Qualification) string {
+	parts := make([]string, len(vars))
+	for i, v := range vars {
+		typ := v.Type
+		if variadic && i == len(vars)-1 {
+			typ = "..." + strings.TrimPrefix(typ, "[]")
+		}
+		parts[i] = f.qualifyType(typ, qual)
+		if v.Name != "" {
+			parts[i] = v.Name + " " + parts[i]
+		}
+	}
+	return strings.Join(parts, ", ")
+}
 
-	// qualify the package path based on qual
+// qualifyType rewrites the package paths in type string ts based on
+// qual.
+func (f defFormatter) qualifyType(ts string, qual graph.Qualification) string {
 	oldPkgPath := f.info.PackageImportPath + "."
 	newPkgPath := f.pkgPath(qual)
 	if newPkgPath != "" {
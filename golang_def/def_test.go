@@ -98,6 +98,32 @@ func TestDefFormatter(t *testing.T) {
 			},
 			wantNames: map[graph.Qualification]string{graph.LanguageWideQualified: "(*a/b.T).Name"},
 		},
+		{
+			// format func types from structured signatures
+			def: &graph.Def{
+				DefKey: graph.DefKey{Repo: "example.com/foo"},
+				Name:   "Name",
+				Kind:   "func",
+				Data: defInfo(DefData{
+					PackageImportPath: "example.com/foo/a",
+					DefInfo: definfo.DefInfo{
+						PkgName: "a", Kind: definfo.Func,
+						Signature: &definfo.Signature{
+							Params: []definfo.VarInfo{
+								{Name: "t", Type: "*example.com/foo/a.T"},
+								{Name: "opts", Type: "[]string"},
+							},
+							Results:  []definfo.VarInfo{{Type: "error"}},
+							Variadic: true,
+						},
+					},
+				}),
+			},
+			wantTypes: map[graph.Qualification]string{
+				graph.Unqualified:  "(t *T, opts ...string) error",
+				graph.DepQualified: "(t *a.T, opts ...string) error",
+			},
+		},
 		{
 			// qualify pkgs with full import path
 			def: &graph.Def{
@@ -106,6 +132,16 @@ func TestDefFormatter(t *testing.T) {
 			},
 			wantNames: map[graph.Qualification]string{graph.LanguageWideQualified: "a/b"},
 		},
+		{
+			// import names
+			def: &graph.Def{
+				Name: "f",
+				Kind: "package",
+				Data: defInfo(DefData{PackageImportPath: "a/b", DefInfo: definfo.DefInfo{PkgName: "b", Kind: definfo.ImportName, ImportedPackage: "fmt"}}),
+			},
+			wantNames: map[graph.Qualification]string{graph.DepQualified: "b.f"},
+			wantTypes: map[graph.Qualification]string{graph.Unqualified: ` "fmt"`},
+		},
 	}
 	for _, test := range tests {
 		sf := newDefFormatter(test.def)
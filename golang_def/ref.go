@@ -0,0 +1,59 @@
+package golang_def
+
+import "sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+
+// Types of the annotations that hold the RefData and DocData of refs
+// and docs (which srclib refs and docs have no room for). Each
+// annotation has the span of its ref or doc.
+const (
+	RefAnnotation = "go-ref"
+	DocAnnotation = "go-doc"
+)
+
+// RefData is extra Go-specific data about a ref. It is only emitted for
+// refs that have any.
+type RefData struct {
+	// DefUnit and DefPath are the ref's target (to tell it apart from
+	// other refs with the same span).
+	DefUnit string
+	DefPath string
+
+	// Via is the chain of embedded fields (outermost first) traversed
+	// to reach the ref's target, if it is a promoted field or method.
+	Via []DefTarget `json:",omitempty"`
+
+	// Deprecated is whether the ref's target is deprecated.
+	Deprecated bool `json:",omitempty"`
+
+	// Range is the line/character range of the ref, if a position
+	// encoding was requested.
+	Range *definfo.Range `json:",omitempty"`
+
+	// Generated is whether the ref is in a generated file.
+	Generated bool `json:",omitempty"`
+
+	// LowConfidence is whether the ref is in a file excluded from the
+	// package's build, so its target may be wrong.
+	LowConfidence bool `json:",omitempty"`
+
+	// Implicit is whether the ref is not written in the source but
+	// implied by a ref to a promoted field or method (whose Via
+	// includes this ref's target).
+	Implicit bool `json:",omitempty"`
+}
+
+// DocData is extra Go-specific data about a doc (of any format). It is
+// only emitted for docs that have any.
+type DocData struct {
+	// Source is where the doc comment was found relative to the def
+	// it documents (one of the gog.DocSource* constants), or empty for
+	// unattached comments.
+	Source string `json:",omitempty"`
+
+	// Range is the line/character range of the doc, if a position
+	// encoding was requested.
+	Range *definfo.Range `json:",omitempty"`
+
+	// Generated is whether the doc is in a generated file.
+	Generated bool `json:",omitempty"`
+}
@@ -13,15 +13,19 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	"golang.org/x/tools/go/gcimporter15"
 
 	"sourcegraph.com/sourcegraph/srclib-go/gog"
 	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
 	defpkg "sourcegraph.com/sourcegraph/srclib-go/golang_def"
+	"sourcegraph.com/sourcegraph/srclib/ann"
 	"sourcegraph.com/sourcegraph/srclib/graph"
 	"sourcegraph.com/sourcegraph/srclib/unit"
 )
@@ -52,7 +56,49 @@ func init() {
 	}
 }
 
-type GraphCmd struct{}
+type GraphCmd struct {
+	MarkdownDocs bool     `long:"markdown-docs" description:"also emit docs rendered as Markdown (text/markdown)"`
+	OmitComments []string `long:"omit-comments" description:"don't emit unattached comments of this class (license, build-constraint, directive, generate, note)" value-name:"CLASS"`
+
+	OmitGenerated bool `long:"omit-generated" description:"don't emit defs, refs, docs and annotations in generated files (with a \"Code generated ... DO NOT EDIT.\" comment)"`
+
+	Capabilities bool `long:"capabilities" description:"record the sensitive capabilities (unsafe, reflect, syscall, exec, cgo) that each def and package uses in def data"`
+
+	Metrics bool `long:"metrics" description:"record size and complexity metrics of funcs, methods and types in def data"`
+
+	PositionEncoding string `long:"position-encoding" description:"also record 0-based line/character ranges of defs, refs and docs in this encoding in def data and ref and doc annotations" choice:"utf-8" choice:"utf-16" choice:"utf-32"`
+}
+
+// options returns the gog.Options corresponding to c's flags.
+func (c *GraphCmd) options() (gog.Options, error) {
+	opt := gog.Options{
+		IncludeDocs:      true,
+		MarkdownDocs:     c.MarkdownDocs,
+		PositionEncoding: c.PositionEncoding,
+		OmitGenerated:    c.OmitGenerated,
+		Capabilities:     c.Capabilities,
+		Metrics:          c.Metrics,
+	}
+	if len(c.OmitComments) > 0 {
+		opt.OmitComments = make(map[string]bool, len(c.OmitComments))
+		for _, class := range c.OmitComments {
+			if !isCommentClass(class) {
+				return gog.Options{}, fmt.Errorf("unrecognized comment class %q (valid classes are %s)", class, strings.Join(gog.CommentClasses, ", "))
+			}
+			opt.OmitComments[class] = true
+		}
+	}
+	return opt, nil
+}
+
+func isCommentClass(class string) bool {
+	for _, c := range gog.CommentClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
 
 var graphCmd GraphCmd
 
@@ -73,11 +119,16 @@ func (c *GraphCmd) Execute(args []string) error {
 		return err
 	}
 
+	opt, err := c.options()
+	if err != nil {
+		return err
+	}
+
 	if err := initBuildContext(); err != nil {
 		return err
 	}
 
-	out, err := Graph(unit)
+	out, err := Graph(unit, opt)
 	if err != nil {
 		return err
 	}
@@ -101,6 +152,11 @@ func (c *GraphCmd) Execute(args []string) error {
 			gd.File = relPath(cwd, gd.File)
 		}
 	}
+	for _, a := range out.Anns {
+		if a.File != "" {
+			a.File = relPath(cwd, a.File)
+		}
+	}
 
 	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
 		return err
@@ -116,21 +172,47 @@ func relPath(base, path string) string {
 	return filepath.ToSlash(rp)
 }
 
-func Graph(unit *unit.SourceUnit) (*graph.Output, error) {
+func Graph(unit *unit.SourceUnit, opt gog.Options) (*graph.Output, error) {
 	pkg, err := UnitDataAsBuildPackage(unit)
 	if err != nil {
 		return nil, err
 	}
 
-	o, err := doGraph(pkg, strings.HasSuffix(unit.Name, "_test"))
+	opt.Files = unitDirFiles(unit)
+
+	o, err := doGraph(pkg, strings.HasSuffix(unit.Name, "_test"), opt)
 	if err != nil {
 		return nil, err
 	}
+	return convertGoOutput(o), nil
+}
 
+// unitDirFiles returns the unit's files (which are relative to the
+// repository root) relative to the unit's directory, with slash
+// separators.
+func unitDirFiles(u *unit.SourceUnit) []string {
+	var files []string
+	for _, f := range u.Files {
+		rel, err := filepath.Rel(u.Dir, f)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		files = append(files, filepath.ToSlash(rel))
+	}
+	return files
+}
+
+// convertGoOutput converts the grapher's output to srclib's.
+func convertGoOutput(o *gog.Output) *graph.Output {
 	o2 := graph.Output{}
 
+	rels := make(map[string][]*gog.Relation)
+	for _, rel := range o.Relations {
+		rels[rel.From.String()] = append(rels[rel.From.String()], rel)
+	}
+
 	for _, gs := range o.Defs {
-		d, err := convertGoDef(gs)
+		d, err := convertGoDef(gs, rels[gs.DefKey.String()])
 		if err != nil {
 			log.Printf("Ignoring def %v due to error in converting to GoDef: %s.", gs, err)
 			continue
@@ -147,8 +229,14 @@ func Graph(unit *unit.SourceUnit) (*graph.Output, error) {
 		}
 		if r != nil {
 			o2.Refs = append(o2.Refs, r)
+			if a, err := convertGoRefData(gr, r); err != nil {
+				log.Printf("Ignoring data of ref %v due to error: %s.", gr, err)
+			} else if a != nil {
+				o2.Anns = append(o2.Anns, a)
+			}
 		}
 	}
+	docSpans := make(map[string]bool)
 	for _, gd := range o.Docs {
 		d, err := convertGoDoc(gd)
 		if err != nil {
@@ -157,13 +245,36 @@ func Graph(unit *unit.SourceUnit) (*graph.Output, error) {
 		}
 		if d != nil {
 			o2.Docs = append(o2.Docs, d)
+
+			// A doc is emitted in several formats, which share their
+			// data.
+			span := fmt.Sprintf("%s:%d-%d", d.File, d.Start, d.End)
+			if docSpans[span] {
+				continue
+			}
+			docSpans[span] = true
+			if a, err := convertGoDocData(gd, d); err != nil {
+				log.Printf("Ignoring data of doc %v due to error: %s.", gd, err)
+			} else if a != nil {
+				o2.Anns = append(o2.Anns, a)
+			}
+		}
+	}
+	for _, ga := range o.Annotations {
+		a, err := convertGoAnnotation(ga)
+		if err != nil {
+			log.Printf("Ignoring annotation %v due to error in converting to GoAnnotation: %s.", ga, err)
+			continue
+		}
+		if a != nil {
+			o2.Anns = append(o2.Anns, a)
 		}
 	}
 
-	return &o2, nil
+	return &o2
 }
 
-func convertGoDef(gs *gog.Def) (*graph.Def, error) {
+func convertGoDef(gs *gog.Def, rels []*gog.Relation) (*graph.Def, error) {
 	resolvedTarget, err := ResolveDep(gs.DefKey.PackageImportPath)
 	if err != nil {
 		return nil, err
@@ -198,6 +309,32 @@ func convertGoDef(gs *gog.Def) (*graph.Def, error) {
 		PackageImportPath: gs.DefKey.PackageImportPath,
 		DefInfo:           gs.DefInfo,
 	}
+	if gs.DefInfo.Kind != definfo.Package {
+		d.Spans = &defpkg.DefSpans{Ident: gs.IdentSpan, Header: gs.HeaderSpan, Full: gs.FullSpan}
+		if gs.BodySpan != [2]uint32{} {
+			body := gs.BodySpan
+			d.Spans.Body = &body
+		}
+		if gs.IdentRange != nil {
+			d.Ranges = &defpkg.DefRanges{
+				Ident:  gs.IdentRange,
+				Decl:   gs.DeclRange,
+				Header: gs.HeaderRange,
+				Body:   gs.BodyRange,
+				Full:   gs.FullRange,
+			}
+		}
+	}
+	for _, rel := range rels {
+		r, err := convertGoRelation(rel)
+		if err != nil {
+			log.Printf("Ignoring relation %v due to error in converting to GoRelation: %s.", rel, err)
+			continue
+		}
+		if r != nil {
+			d.Relations = append(d.Relations, *r)
+		}
+	}
 	def.Data, err = json.Marshal(d)
 	if err != nil {
 		return nil, err
@@ -211,6 +348,31 @@ func convertGoDef(gs *gog.Def) (*graph.Def, error) {
 	return def, nil
 }
 
+func convertGoRelation(rel *gog.Relation) (*defpkg.DefRelation, error) {
+	target, err := convertGoDefTarget(rel.To)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	return &defpkg.DefRelation{Kind: rel.Kind, DefTarget: *target}, nil
+}
+
+func convertGoDefTarget(key *gog.DefKey) (*defpkg.DefTarget, error) {
+	resolvedTarget, err := ResolveDep(key.PackageImportPath)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedTarget == nil {
+		return nil, nil
+	}
+
+	return &defpkg.DefTarget{
+		DefRepo:     filepath.ToSlash(uriOrEmpty(resolvedTarget.ToRepoCloneURL)),
+		DefUnitType: resolvedTarget.ToUnitType,
+		DefUnit:     resolvedTarget.ToUnit,
+		DefPath:     filepath.ToSlash(pathOrDot(filepath.Join(key.Path...))),
+	}, nil
+}
+
 func convertGoRef(gr *gog.Ref) (*graph.Ref, error) {
 	resolvedTarget, err := ResolveDep(gr.Def.PackageImportPath)
 	if err != nil {
@@ -241,6 +403,44 @@ func convertGoRef(gr *gog.Ref) (*graph.Ref, error) {
 	}, nil
 }
 
+// convertGoRefData returns an annotation (of type
+// defpkg.RefAnnotation) holding the data of gr that r, its srclib ref,
+// can't hold, or nil if there is none.
+func convertGoRefData(gr *gog.Ref, r *graph.Ref) (*ann.Ann, error) {
+	d := defpkg.RefData{DefUnit: r.DefUnit, DefPath: r.DefPath}
+	for _, key := range gr.Via {
+		target, err := convertGoDefTarget(key)
+		if err != nil {
+			return nil, err
+		}
+		if target != nil {
+			d.Via = append(d.Via, *target)
+		}
+	}
+	d.Deprecated = gr.Deprecated
+	d.Range = gr.Range
+	d.Generated = gr.Generated
+	d.LowConfidence = gr.LowConfidence
+	d.Implicit = gr.Implicit
+	if d.Via == nil && !d.Deprecated && d.Range == nil && !d.Generated && !d.LowConfidence && !d.Implicit {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return &ann.Ann{
+		UnitType: "GoPackage",
+		Unit:     r.Unit,
+		Type:     defpkg.RefAnnotation,
+		File:     r.File,
+		Start:    r.Start,
+		End:      r.End,
+		Data:     data,
+	}, nil
+}
+
 func convertGoDoc(gd *gog.Doc) (*graph.Doc, error) {
 	var key graph.DefKey
 	if gd.DefKey != nil {
@@ -274,6 +474,54 @@ func convertGoDoc(gd *gog.Doc) (*graph.Doc, error) {
 	}, nil
 }
 
+// convertGoDocData returns an annotation (of type
+// defpkg.DocAnnotation) holding the data of gd that d, its srclib doc,
+// can't hold, or nil if there is none.
+func convertGoDocData(gd *gog.Doc, d *graph.Doc) (*ann.Ann, error) {
+	if gd.Source == "" && gd.Range == nil && !gd.Generated {
+		return nil, nil
+	}
+	data, err := json.Marshal(defpkg.DocData{Source: gd.Source, Range: gd.Range, Generated: gd.Generated})
+	if err != nil {
+		return nil, err
+	}
+	return &ann.Ann{
+		UnitType: "GoPackage",
+		Unit:     d.DocUnit,
+		Type:     defpkg.DocAnnotation,
+		File:     d.File,
+		Start:    d.Start,
+		End:      d.End,
+		Data:     data,
+	}, nil
+}
+
+func convertGoAnnotation(ga *gog.Annotation) (*ann.Ann, error) {
+	resolvedUnit, err := ResolveDep(ga.Unit)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedUnit == nil {
+		return nil, nil
+	}
+
+	a := &ann.Ann{
+		UnitType: resolvedUnit.ToUnitType,
+		Unit:     resolvedUnit.ToUnit,
+		Type:     ga.Type,
+		File:     filepath.ToSlash(ga.File),
+		Start:    ga.Span[0],
+		End:      ga.Span[1],
+	}
+	if ga.Data != nil {
+		a.Data, err = json.Marshal(ga.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
 func uriOrEmpty(cloneURL string) string {
 	if cloneURL == "" {
 		return ""
@@ -295,7 +543,7 @@ func treePath(path string) string {
 	return "./" + path
 }
 
-func doGraph(buildPkg *build.Package, testPkg bool) (*gog.Output, error) {
+func doGraph(buildPkg *build.Package, testPkg bool, opt gog.Options) (*gog.Output, error) {
 	fset := token.NewFileSet()
 
 	var allImports []string
@@ -306,6 +554,7 @@ func doGraph(buildPkg *build.Package, testPkg bool) (*gog.Output, error) {
 	if err != nil {
 		return nil, err
 	}
+	opt.DepDeprecations = loadDeprecations(allImports, buildPkg.ImportPath, buildPkg.Dir)
 
 	var allGoFiles []string
 	allGoFiles = append(allGoFiles, buildPkg.GoFiles...)
@@ -313,20 +562,22 @@ func doGraph(buildPkg *build.Package, testPkg bool) (*gog.Output, error) {
 	allGoFiles = append(allGoFiles, buildPkg.TestGoFiles...)
 
 	if !testPkg {
-		// graph non-test package
-		return doGraphFiles(fset, buildPkg.ImportPath, buildPkg.Dir, allGoFiles, dependencies)
-	}
+		opt.CSymbols = loadCSymbols(buildPkg)
+		opt.AsmSymbols = loadAsmSymbols(buildPkg)
+		opt.ProtoFiles = loadProtoFiles(buildPkg)
 
-	// prepare type info for non-test package, needed as a dependency for graphing the test package
-	var files []*ast.File
-	for _, name := range allGoFiles {
-		file, err := parser.ParseFile(fset, filepath.Join(buildPkg.Dir, name), nil, parser.ParseComments)
+		// graph non-test package
+		files := parseFiles(fset, buildPkg.Dir, allGoFiles)
+		o, err := doGraphFiles(fset, buildPkg.ImportPath, files, dependencies, opt)
 		if err != nil {
-			log.Printf("could not parse %s: %s", name, err)
-			continue
+			return nil, err
 		}
-		files = append(files, file)
+		o.Append(graphExcludedFiles(fset, buildPkg, files, dependencies, opt))
+		return o, nil
 	}
+
+	// prepare type info for non-test package, needed as a dependency for graphing the test package
+	files := parseFiles(fset, buildPkg.Dir, allGoFiles)
 	typesConfig := &types.Config{
 		Importer:    mapImporter(dependencies),
 		FakeImportC: true,
@@ -339,25 +590,31 @@ func doGraph(buildPkg *build.Package, testPkg bool) (*gog.Output, error) {
 		log.Println("type checker error:", err) // see comment above
 	}
 	dependencies[buildPkg.ImportPath] = typesPkg
+	opt.DepDeprecations[buildPkg.ImportPath] = gog.FileDeprecations(fset, files)
 
 	// graph test package
-	return doGraphFiles(fset, buildPkg.ImportPath+"_test", buildPkg.Dir, buildPkg.XTestGoFiles, dependencies)
+	return doGraphFiles(fset, buildPkg.ImportPath+"_test", parseFiles(fset, buildPkg.Dir, buildPkg.XTestGoFiles), dependencies, opt)
 }
 
-func doGraphFiles(fset *token.FileSet, importPath string, srcDir string, fileNames []string, dependencies map[string]*types.Package) (*gog.Output, error) {
-	if len(fileNames) == 0 {
-		return &gog.Output{}, nil
-	}
-
+// parseFiles parses the named files in dir, skipping (and logging) any
+// that can't be parsed.
+func parseFiles(fset *token.FileSet, dir string, names []string) []*ast.File {
 	var files []*ast.File
-	for _, name := range fileNames {
-		file, err := parser.ParseFile(fset, filepath.Join(srcDir, name), nil, parser.ParseComments)
+	for _, name := range names {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
 		if err != nil {
 			log.Printf("could not parse %s: %s", name, err)
 			continue
 		}
 		files = append(files, file)
 	}
+	return files
+}
+
+func doGraphFiles(fset *token.FileSet, importPath string, files []*ast.File, dependencies map[string]*types.Package, opt gog.Options) (*gog.Output, error) {
+	if len(files) == 0 {
+		return &gog.Output{}, nil
+	}
 
 	typesConfig := &types.Config{
 		Importer:    mapImporter(dependencies),
@@ -378,7 +635,163 @@ func doGraphFiles(fset *token.FileSet, importPath string, srcDir string, fileNam
 		log.Println("type checker error:", err) // see comment above
 	}
 
-	return gog.Graph(fset, files, typesPkg, typesInfo, true), nil
+	return gog.Graph(fset, files, typesPkg, typesInfo, opt), nil
+}
+
+// graphExcludedFiles graphs the files that are excluded from buildPkg's
+// build (e.g., by build constraints), so that they have defs and refs
+// too. Each file in the package is type-checked along with the
+// package's (already parsed) files, and any other file on its own, on a
+// best-effort basis; see gog.Options.Excluded.
+func graphExcludedFiles(fset *token.FileSet, buildPkg *build.Package, files []*ast.File, dependencies map[string]*types.Package, opt gog.Options) *gog.Output {
+	var output gog.Output
+	if len(buildPkg.IgnoredGoFiles) == 0 {
+		return &output
+	}
+	opt.Excluded = true
+
+	for _, name := range buildPkg.IgnoredGoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(buildPkg.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			log.Printf("could not parse excluded file %s: %s", name, err)
+			continue
+		}
+
+		// Excluded files may import packages that the package's
+		// other files don't.
+		var imports []string
+		for _, imp := range file.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			if _, ok := dependencies[path]; !ok {
+				imports = append(imports, path)
+			}
+		}
+		if len(imports) > 0 {
+			deps, err := loadDependencies(imports, buildPkg.ImportPath, buildPkg.Dir, fset)
+			if err != nil {
+				log.Printf("could not load dependencies of excluded file %s: %s", name, err)
+			}
+			for path, pkg := range deps {
+				dependencies[path] = pkg
+			}
+		}
+
+		checkFiles := []*ast.File{file}
+		fileOpt := opt
+		if file.Name.Name == buildPkg.Name {
+			// The package's own declarations take precedence over
+			// any that the excluded file redeclares.
+			checkFiles = append(append([]*ast.File{}, files...), file)
+			fileOpt.PackageFiles = files
+		}
+		typesConfig := &types.Config{
+			Importer:    mapImporter(dependencies),
+			FakeImportC: true,
+			Error: func(err error) {
+				// errors are expected (e.g., redeclarations and
+				// platform-specific imports); use best-effort type
+				// checking output
+			},
+		}
+		typesInfo := &types.Info{
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		}
+		typesPkg, _ := typesConfig.Check(buildPkg.ImportPath, fset, checkFiles, typesInfo)
+		output.Append(gog.Graph(fset, []*ast.File{file}, typesPkg, typesInfo, fileOpt))
+	}
+	return &output
+}
+
+// loadCSymbols scans buildPkg's C source and header files for the C
+// symbols that its cgo files can refer to.
+func loadCSymbols(buildPkg *build.Package) []*gog.CSymbol {
+	var syms []*gog.CSymbol
+	for _, name := range append(append([]string{}, buildPkg.CFiles...), buildPkg.HFiles...) {
+		filename := filepath.Join(buildPkg.Dir, name)
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			log.Printf("could not read %s: %s", name, err)
+			continue
+		}
+		syms = append(syms, gog.ScanCFile(filename, src)...)
+	}
+	return syms
+}
+
+// loadAsmSymbols scans buildPkg's assembly files for the symbols they
+// define and refer to.
+func loadAsmSymbols(buildPkg *build.Package) []*gog.AsmSymbol {
+	var syms []*gog.AsmSymbol
+	for _, name := range buildPkg.SFiles {
+		filename := filepath.Join(buildPkg.Dir, name)
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			log.Printf("could not read %s: %s", name, err)
+			continue
+		}
+		syms = append(syms, gog.ScanAsmFile(filename, src)...)
+	}
+	return syms
+}
+
+// loadProtoFiles scans the .proto files that buildPkg's generated Go
+// files name as their source (see protoCandidates).
+func loadProtoFiles(buildPkg *build.Package) []*gog.ProtoFile {
+	seen := make(map[string]bool)
+	var protoFiles []*gog.ProtoFile
+	for _, name := range buildPkg.GoFiles {
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(buildPkg.Dir, name), nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		source := gog.ProtoSource(f)
+		if source == "" || seen[source] {
+			continue
+		}
+		seen[source] = true
+
+		for _, filename := range protoCandidates(buildPkg.Dir, cwd, source) {
+			src, err := ioutil.ReadFile(filename)
+			if err != nil {
+				continue
+			}
+			protoFiles = append(protoFiles, gog.ScanProtoFile(filename, source, src))
+			break
+		}
+	}
+	return protoFiles
+}
+
+// protoCandidates returns the filenames that the .proto file named
+// source by the generated Go files in dir can have. It is looked for
+// relative to dir and its ancestors up to the repository root (since
+// its name is relative to protoc's import path), and then in dir
+// itself. Files outside the repository are never candidates, and each
+// candidate is returned once.
+func protoCandidates(dir, root, source string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(filename string) {
+		if pathHasPrefix(filename, root) && !seen[filename] {
+			seen[filename] = true
+			candidates = append(candidates, filename)
+		}
+	}
+	for d := dir; pathHasPrefix(d, root); d = filepath.Dir(d) {
+		add(filepath.Join(d, filepath.FromSlash(source)))
+		if d == root || filepath.Dir(d) == d {
+			break
+		}
+	}
+	add(filepath.Join(dir, path.Base(source)))
+	return candidates
 }
 
 type mapImporter map[string]*types.Package
@@ -427,3 +840,67 @@ func loadDependencies(imports []string, currentPkg string, srcDir string, fset *
 
 	return dependencies, nil
 }
+
+// deprecationsCache caches the deprecations of packages by directory.
+var deprecationsCache = struct {
+	data map[string]gog.Deprecations
+	mu   sync.Mutex
+}{data: map[string]gog.Deprecations{}}
+
+// loadDeprecations parses the source files of the imported packages to
+// find their deprecated defs. Unlike the type information loaded by
+// loadDependencies, this requires the packages' doc comments.
+func loadDeprecations(imports []string, currentPkg string, srcDir string) map[string]gog.Deprecations {
+	deprecations := map[string]gog.Deprecations{}
+	for _, path := range imports {
+		if path == "unsafe" || path == "C" || path == currentPkg {
+			continue
+		}
+		if _, seen := deprecations[path]; seen {
+			continue
+		}
+
+		impPkg, err := buildContext.Import(path, srcDir, 0)
+		if err != nil {
+			log.Printf("could not find deprecations in %s: %s", path, err)
+			continue
+		}
+		deprecations[path] = loadPkgDeprecations(impPkg)
+	}
+	return deprecations
+}
+
+// loadPkgDeprecations returns the deprecations in pkg's source files.
+// Only the package clauses of the files that don't mention
+// "Deprecated: " are parsed (to find the package doc).
+func loadPkgDeprecations(pkg *build.Package) gog.Deprecations {
+	deprecationsCache.mu.Lock()
+	defer deprecationsCache.mu.Unlock()
+	if deps, ok := deprecationsCache.data[pkg.Dir]; ok {
+		return deps
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...) {
+		filename := filepath.Join(pkg.Dir, name)
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			log.Printf("could not read %s: %s", name, err)
+			continue
+		}
+		mode := parser.ParseComments
+		if !bytes.Contains(src, []byte("Deprecated: ")) {
+			mode |= parser.PackageClauseOnly
+		}
+		file, err := parser.ParseFile(fset, filename, src, mode)
+		if err != nil {
+			log.Printf("could not parse %s: %s", name, err)
+			continue
+		}
+		files = append(files, file)
+	}
+	deps := gog.FileDeprecations(fset, files)
+	deprecationsCache.data[pkg.Dir] = deps
+	return deps
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/srclib-go/gog"
+	"sourcegraph.com/sourcegraph/srclib-go/gog/definfo"
+	defpkg "sourcegraph.com/sourcegraph/srclib-go/golang_def"
+	"sourcegraph.com/sourcegraph/srclib/ann"
+	"sourcegraph.com/sourcegraph/srclib/dep"
+	"sourcegraph.com/sourcegraph/srclib/unit"
+)
+
+func init() {
+	resolveCache.Put("foo", &dep.ResolvedTarget{ToUnit: "foo", ToUnitType: "GoPackage"})
+}
+
+// annData returns the data of the annotations of type typ in anns,
+// unmarshaled into values returned by v.
+func annData(t *testing.T, anns []*ann.Ann, typ string, v func() interface{}) []interface{} {
+	var data []interface{}
+	for _, a := range anns {
+		if a.Type != typ {
+			continue
+		}
+		d := v()
+		if err := json.Unmarshal(a.Data, d); err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, d)
+	}
+	return data
+}
+
+func TestConvertPromotedRefs(t *testing.T) {
+	// x.M, where M is promoted from x's embedded field B
+	o := &gog.Output{Refs: []*gog.Ref{
+		{Unit: "foo", File: "foo.go", Span: [2]uint32{10, 11}, Def: &gog.DefKey{PackageImportPath: "foo", Path: []string{"B", "M"}},
+			Via: []*gog.DefKey{{PackageImportPath: "foo", Path: []string{"A", "B"}}}},
+		{Unit: "foo", File: "foo.go", Span: [2]uint32{10, 11}, Def: &gog.DefKey{PackageImportPath: "foo", Path: []string{"A", "B"}}, Implicit: true},
+		{Unit: "foo", File: "foo.go", Span: [2]uint32{8, 9}, Def: &gog.DefKey{PackageImportPath: "foo", Path: []string{"x"}}},
+	}}
+	out := convertGoOutput(o)
+
+	if len(out.Refs) != 3 {
+		t.Fatalf("got %d refs, want 3", len(out.Refs))
+	}
+	if out.Refs[0].DefPath != "B/M" || out.Refs[1].DefPath != "A/B" || out.Refs[2].DefPath != "x" {
+		t.Errorf("got refs to %q, %q and %q, want B/M, A/B and x", out.Refs[0].DefPath, out.Refs[1].DefPath, out.Refs[2].DefPath)
+	}
+
+	data := annData(t, out.Anns, defpkg.RefAnnotation, func() interface{} { return new(defpkg.RefData) })
+	want := []interface{}{
+		&defpkg.RefData{
+			DefUnit: "foo",
+			DefPath: "B/M",
+			Via:     []defpkg.DefTarget{{DefUnitType: "GoPackage", DefUnit: "foo", DefPath: "A/B"}},
+		},
+		&defpkg.RefData{DefUnit: "foo", DefPath: "A/B", Implicit: true},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got ref data %+v, want %+v", data, want)
+	}
+	if len(out.Anns) == 0 {
+		return
+	}
+	if a := out.Anns[0]; a.File != "foo.go" || a.Start != 10 || a.End != 11 {
+		t.Errorf("got ref data annotation at %s:%d-%d, want foo.go:10-11", a.File, a.Start, a.End)
+	}
+}
+
+func TestConvertDeprecatedRefs(t *testing.T) {
+	o := &gog.Output{Refs: []*gog.Ref{
+		{Unit: "foo", File: "foo.go", Span: [2]uint32{1, 2}, Def: &gog.DefKey{PackageImportPath: "foo", Path: []string{"Old"}}, Deprecated: true},
+		{Unit: "foo", File: "foo.go", Span: [2]uint32{3, 4}, Def: &gog.DefKey{PackageImportPath: "foo", Path: []string{"New"}}},
+	}}
+	out := convertGoOutput(o)
+	data := annData(t, out.Anns, defpkg.RefAnnotation, func() interface{} { return new(defpkg.RefData) })
+	want := []interface{}{&defpkg.RefData{DefUnit: "foo", DefPath: "Old", Deprecated: true}}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got ref data %+v, want %+v", data, want)
+	}
+}
+
+func TestConvertRanges(t *testing.T) {
+	r := &definfo.Range{Start: definfo.Position{Line: 1, Character: 2}, End: definfo.Position{Line: 1, Character: 3}}
+	o := &gog.Output{
+		Refs: []*gog.Ref{{Unit: "foo", File: "foo.go", Span: [2]uint32{1, 2}, Def: &gog.DefKey{PackageImportPath: "foo", Path: []string{"X"}}, Range: r}},
+		Docs: []*gog.Doc{
+			{DefKey: &gog.DefKey{PackageImportPath: "foo", Path: []string{"X"}}, Unit: "foo", Format: "text/html", File: "foo.go", Span: [2]uint32{5, 9}, Range: r},
+			{DefKey: &gog.DefKey{PackageImportPath: "foo", Path: []string{"X"}}, Unit: "foo", Format: "text/plain", File: "foo.go", Span: [2]uint32{5, 9}, Range: r},
+		},
+	}
+	out := convertGoOutput(o)
+
+	refData := annData(t, out.Anns, defpkg.RefAnnotation, func() interface{} { return new(defpkg.RefData) })
+	if want := []interface{}{&defpkg.RefData{DefUnit: "foo", DefPath: "X", Range: r}}; !reflect.DeepEqual(refData, want) {
+		t.Errorf("got ref data %+v, want %+v", refData, want)
+	}
+	docData := annData(t, out.Anns, defpkg.DocAnnotation, func() interface{} { return new(defpkg.DocData) })
+	if want := []interface{}{&defpkg.DocData{Range: r}}; !reflect.DeepEqual(docData, want) {
+		t.Errorf("got doc data %+v, want %+v (once for both formats)", docData, want)
+	}
+}
+
+func TestConvertGeneratedRefsAndDocs(t *testing.T) {
+	key := &gog.DefKey{PackageImportPath: "foo", Path: []string{"X"}}
+	o := &gog.Output{
+		Refs: []*gog.Ref{
+			{Unit: "foo", File: "foo.pb.go", Span: [2]uint32{1, 2}, Def: key, Generated: true},
+			{Unit: "foo", File: "foo.go", Span: [2]uint32{1, 2}, Def: key},
+		},
+		Docs: []*gog.Doc{{DefKey: key, Unit: "foo", Format: "text/plain", File: "foo.pb.go", Span: [2]uint32{5, 9}, Generated: true}},
+	}
+	out := convertGoOutput(o)
+
+	refData := annData(t, out.Anns, defpkg.RefAnnotation, func() interface{} { return new(defpkg.RefData) })
+	if want := []interface{}{&defpkg.RefData{DefUnit: "foo", DefPath: "X", Generated: true}}; !reflect.DeepEqual(refData, want) {
+		t.Errorf("got ref data %+v, want %+v", refData, want)
+	}
+	docData := annData(t, out.Anns, defpkg.DocAnnotation, func() interface{} { return new(defpkg.DocData) })
+	if want := []interface{}{&defpkg.DocData{Generated: true}}; !reflect.DeepEqual(docData, want) {
+		t.Errorf("got doc data %+v, want %+v", docData, want)
+	}
+}
+
+func TestConvertDocSource(t *testing.T) {
+	key := &gog.DefKey{PackageImportPath: "foo", Path: []string{"X"}}
+	o := &gog.Output{
+		Docs: []*gog.Doc{
+			{DefKey: key, Unit: "foo", Format: "text/html", Source: gog.DocSourceTrailing, File: "foo.go", Span: [2]uint32{5, 9}},
+			{DefKey: key, Unit: "foo", Format: "text/plain", Source: gog.DocSourceTrailing, File: "foo.go", Span: [2]uint32{5, 9}},
+			{DefKey: key, Unit: "foo", Format: "text/plain", File: "foo.go", Span: [2]uint32{20, 30}},
+		},
+	}
+	out := convertGoOutput(o)
+
+	docData := annData(t, out.Anns, defpkg.DocAnnotation, func() interface{} { return new(defpkg.DocData) })
+	if want := []interface{}{&defpkg.DocData{Source: gog.DocSourceTrailing}}; !reflect.DeepEqual(docData, want) {
+		t.Errorf("got doc data %+v, want %+v", docData, want)
+	}
+}
+
+func TestConvertLowConfidenceRefs(t *testing.T) {
+	o := &gog.Output{
+		Refs: []*gog.Ref{{Unit: "foo", File: "foo_windows.go", Span: [2]uint32{1, 2}, Def: &gog.DefKey{PackageImportPath: "foo", Path: []string{"X"}}, LowConfidence: true}},
+	}
+	out := convertGoOutput(o)
+
+	refData := annData(t, out.Anns, defpkg.RefAnnotation, func() interface{} { return new(defpkg.RefData) })
+	if want := []interface{}{&defpkg.RefData{DefUnit: "foo", DefPath: "X", LowConfidence: true}}; !reflect.DeepEqual(refData, want) {
+		t.Errorf("got ref data %+v, want %+v", refData, want)
+	}
+}
+
+func TestUnitDirFiles(t *testing.T) {
+	u := &unit.SourceUnit{Info: unit.Info{
+		Dir:   "sub/pkg",
+		Files: []string{"sub/pkg/foo.go", "sub/pkg/static/index.html", "sub/other.go"},
+	}}
+	if got, want := unitDirFiles(u), []string{"foo.go", "static/index.html"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	u.Dir, u.Files = ".", []string{"foo.go", "static/index.html"}
+	if got, want := unitDirFiles(u), []string{"foo.go", "static/index.html"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q in the root unit, want %q", got, want)
+	}
+}
+
+func TestProtoCandidates(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	tests := []struct {
+		dir, source string
+		want        []string
+	}{
+		{"/repo/api/foo/v1", "api/foo/v1/foo.proto", []string{
+			"/repo/api/foo/v1/api/foo/v1/foo.proto",
+			"/repo/api/foo/api/foo/v1/foo.proto",
+			"/repo/api/api/foo/v1/foo.proto",
+			"/repo/api/foo/v1/foo.proto",
+		}},
+		{"/repo", "foo.proto", []string{"/repo/foo.proto"}},
+		{"/repo/api", "foo.proto", []string{"/repo/api/foo.proto", "/repo/foo.proto"}},
+		// files outside the repository are skipped
+		{"/repo/api", "../../etc/foo.proto", []string{"/repo/api/foo.proto"}},
+	}
+	for _, test := range tests {
+		var want []string
+		for _, f := range test.want {
+			want = append(want, filepath.FromSlash(f))
+		}
+		if got := protoCandidates(filepath.FromSlash(test.dir), root, test.source); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s in %s: got %q, want %q", test.source, test.dir, got, want)
+		}
+	}
+}
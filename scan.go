@@ -165,6 +165,11 @@ func scan(scanDir string) ([]*unit.SourceUnit, error) {
 		files = append(files, pkg.SwigCXXFiles...)
 		files = append(files, pkg.SysoFiles...)
 		files = append(files, pkg.TestGoFiles...)
+		files = append(files, embedFiles(pkg.Dir, append(append([]string{}, pkg.EmbedPatterns...), pkg.TestEmbedPatterns...))...)
+
+		var xtestFiles []string
+		xtestFiles = append(xtestFiles, pkg.XTestGoFiles...)
+		xtestFiles = append(xtestFiles, embedFiles(pkg.Dir, pkg.XTestEmbedPatterns)...)
 
 		// Collect all imports. We use a map to remove duplicates.
 		var imports []string
@@ -230,7 +235,7 @@ func scan(scanDir string) ([]*unit.SourceUnit, error) {
 				},
 				Info: unit.Info{
 					Dir:          pkg.Dir,
-					Files:        pkg.XTestGoFiles,
+					Files:        xtestFiles,
 					Data:         pkgData,
 					Dependencies: deps,
 					Ops:          map[string][]byte{"depresolve": nil, "graph": nil},
@@ -401,3 +406,40 @@ func writePkgObj(buildPkg *build.Package) error {
 	}
 	return ioutil.WriteFile(buildPkg.PkgObj, gcimporter.BExportData(fset, typesPkg), 0666)
 }
+
+// embedFiles returns the files (relative to dir) matched by the
+// //go:embed patterns of the package in dir, so that they are part of
+// the source unit and can be referenced by the embedding vars.
+func embedFiles(dir string, patterns []string) []string {
+	seen := map[string]bool{}
+	var files []string
+	add := func(path string) {
+		if rel, err := filepath.Rel(dir, path); err == nil && !seen[rel] {
+			seen[rel] = true
+			files = append(files, rel)
+		}
+	}
+	for _, pattern := range patterns {
+		all := strings.HasPrefix(pattern, "all:")
+		matches, _ := filepath.Glob(filepath.Join(dir, strings.TrimPrefix(pattern, "all:")))
+		for _, m := range matches {
+			filepath.Walk(m, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return nil
+				}
+				if name := info.Name(); path != m && !all && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !info.IsDir() {
+					add(path)
+				}
+				return nil
+			})
+		}
+	}
+	sort.Strings(files)
+	return files
+}
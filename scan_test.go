@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEmbedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srclib-go-embed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"static/index.html", "static/.hidden", "static/_draft/a.html", "testdata/golden.txt"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"static"}, []string{"static/index.html"}},
+		{[]string{"all:static"}, []string{"static/.hidden", "static/_draft/a.html", "static/index.html"}},
+		{[]string{"static/*.html", "testdata/golden.txt"}, []string{"static/index.html", "testdata/golden.txt"}},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, f := range embedFiles(dir, test.patterns) {
+			got = append(got, filepath.ToSlash(f))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.patterns, got, test.want)
+		}
+	}
+}